@@ -17,12 +17,20 @@ func main() {
 	// Initialize app
 	godotenv.Load()
 	utils.InitCacheManager()
-	sessionMgr := utils.InitSessionManager()
 
 	// Initialize database
 	if err := utils.InitDatabase(); err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
+	utils.DB.StartSessionCleanup()
+	utils.DB.StartElevationExpiry()
+	utils.DB.StartApprovalExpiry()
+	utils.LoginGuard.Cleanup()
+
+	sessionMgr, err := utils.InitSessionManager()
+	if err != nil {
+		log.Fatal("Failed to initialize session store:", err)
+	}
 
 	if err := utils.Garage.LoadConfig(); err != nil {
 		log.Println("Cannot load garage config!", err)
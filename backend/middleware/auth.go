@@ -2,30 +2,180 @@ package middleware
 
 import (
 	"errors"
+	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
+	"log"
 	"net/http"
+	"strings"
 )
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		auth := utils.Session.Get(r, "authenticated")
-		userID := utils.Session.Get(r, "user_id")
+		user, auth, err := authenticate(r)
+		if err != nil {
+			utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+			return
+		}
+		auth.User = user
+
+		// Impersonating admins may look around as the user but not change anything,
+		// except to end the impersonation or log out
+		if auth.ImpersonatorID != "" {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead &&
+				!(r.Method == http.MethodDelete && r.URL.Path == "/auth/impersonate") &&
+				!(r.Method == http.MethodPost && r.URL.Path == "/auth/logout") {
+				utils.ResponseErrorStatus(w, utils.ErrImpersonationReadOnly, http.StatusForbidden)
+				return
+			}
 
-		// Check if user is authenticated
-		if auth == nil || !auth.(bool) || userID == nil {
-			utils.ResponseErrorStatus(w, errors.New("unauthorized"), http.StatusUnauthorized)
+			next.ServeHTTP(w, utils.WithAuth(r, auth))
 			return
 		}
 
-		// Verify user still exists and is enabled
-		user, err := utils.DB.GetUser(userID.(string))
-		if err != nil || !user.Enabled {
-			// Clear invalid session
-			utils.Session.Clear(r)
-			utils.ResponseErrorStatus(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		// Users required to use 2FA may only reach the auth endpoints until they enroll.
+		// Proxy logins are left to the multi-factor policy of the proxy.
+		if auth.Method != utils.AuthMethodToken && auth.Method != utils.AuthMethodProxy &&
+			!strings.HasPrefix(r.URL.Path, "/auth/") &&
+			utils.DB.RequiresTwoFactorSetup(user) {
+			utils.ResponseErrorStatus(w, errors.New("two-factor authentication setup required"), http.StatusForbidden)
+			return
+		}
+
+		// Users with a temporary or expired password must change it first
+		if auth.Method != utils.AuthMethodToken && !strings.HasPrefix(r.URL.Path, "/auth/") &&
+			utils.DB.RequiresPasswordChange(user) {
+			utils.ResponseErrorStatus(w, errors.New("password change required"), http.StatusForbidden)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, utils.WithAuth(r, auth))
 	})
 }
+
+// authenticate resolves the user from a bearer token or the cookie session
+func authenticate(r *http.Request) (*schema.User, *utils.AuthInfo, error) {
+	unauthorized := errors.New("unauthorized")
+
+	// Identity headers from a trusted authenticating reverse proxy
+	if identity, ok := utils.ForwardAuth.GetIdentity(r); ok {
+		user, err := utils.ForwardAuth.Provision(identity)
+		if err != nil {
+			log.Printf("Forward auth failed for %s: %v", identity.Username, err)
+			return nil, nil, unauthorized
+		}
+
+		return user, &utils.AuthInfo{
+			UserID: user.ID,
+			Method: utils.AuthMethodProxy,
+		}, nil
+	}
+
+	// Bearer token authentication for API clients
+	if token, ok := getBearerToken(r); ok && strings.HasPrefix(token, schema.AccessTokenPrefix) {
+		accessToken, err := utils.DB.GetAccessTokenBySecret(token)
+		if err != nil {
+			return nil, nil, unauthorized
+		}
+
+		user, err := utils.DB.GetUser(accessToken.UserID)
+		if err != nil || !user.Enabled {
+			return nil, nil, unauthorized
+		}
+
+		utils.DB.TouchAccessToken(accessToken.ID)
+
+		return user, &utils.AuthInfo{
+			UserID: user.ID,
+			Method: utils.AuthMethodToken,
+			Scopes: accessToken.Scopes,
+		}, nil
+	} else if ok {
+		session, err := utils.DB.GetSessionByToken(token)
+		if err != nil {
+			return nil, nil, unauthorized
+		}
+
+		user, err := utils.DB.GetUser(session.UserID)
+		if err != nil || !user.Enabled {
+			return nil, nil, unauthorized
+		}
+
+		utils.DB.TouchSession(session.ID, utils.GetClientIP(r), r.UserAgent())
+
+		auth := &utils.AuthInfo{
+			UserID:    user.ID,
+			SessionID: session.ID,
+			Method:    utils.AuthMethodBearer,
+		}
+		return impersonate(r, session, user, auth), auth, nil
+	}
+
+	auth := utils.Session.Get(r, "authenticated")
+	userID := utils.Session.Get(r, "user_id")
+
+	// Check if user is authenticated
+	if auth == nil || !auth.(bool) || userID == nil {
+		return nil, nil, unauthorized
+	}
+
+	// Cookie sessions are only valid while their session record exists
+	sessionID, _ := utils.Session.Get(r, "session_id").(string)
+	session, err := utils.DB.GetSession(sessionID)
+	if err != nil || session.UserID != userID.(string) {
+		utils.Session.Destroy(r)
+		return nil, nil, unauthorized
+	}
+
+	// Verify user still exists and is enabled
+	user, err := utils.DB.GetUser(session.UserID)
+	if err != nil || !user.Enabled {
+		// Clear invalid session
+		utils.Session.Destroy(r)
+		return nil, nil, unauthorized
+	}
+
+	utils.DB.TouchSession(sessionID, utils.GetClientIP(r), r.UserAgent())
+
+	authInfo := &utils.AuthInfo{
+		UserID:    user.ID,
+		SessionID: sessionID,
+		Method:    utils.AuthMethodSession,
+	}
+	return impersonate(r, session, user, authInfo), authInfo, nil
+}
+
+// impersonate switches the request to the user impersonated by the session, ending the
+// impersonation once it has expired or the user can no longer be impersonated
+func impersonate(r *http.Request, session *schema.Session, user *schema.User, auth *utils.AuthInfo) *schema.User {
+	impersonation := session.Impersonation
+	if impersonation == nil {
+		return user
+	}
+
+	target, err := utils.DB.GetUser(impersonation.UserID)
+	if err == nil && target.Enabled && impersonation.IsActive() {
+		auth.UserID = target.ID
+		auth.ImpersonatorID = user.ID
+		return target
+	}
+
+	if _, err := utils.DB.StopImpersonation(session.ID); err == nil {
+		event := utils.NewAuditEvent(r, schema.AuditImpersonationEnded)
+		event.ActorID = user.ID
+		event.UserID = impersonation.UserID
+		event.Message = "impersonation expired"
+		utils.DB.RecordAudit(event)
+	}
+	return user
+}
+
+// getBearerToken extracts the token from an "Authorization: Bearer" header
+func getBearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+
+	token := strings.TrimSpace(header[7:])
+	return token, len(token) > 0
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"errors"
+	"khairul169/garage-webui/utils"
+	"net/http"
+)
+
+// CSRFMiddleware requires a valid CSRF token on state-changing requests that are
+// authenticated by the browser, i.e. by the session cookie or a reverse proxy.
+// Bearer token requests are exempt as browsers never attach them automatically.
+func CSRFMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		auth := utils.GetAuth(r)
+		if auth != nil && (auth.Method == utils.AuthMethodBearer || auth.Method == utils.AuthMethodToken) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !utils.VerifyCSRFToken(r) {
+			utils.ResponseErrorStatus(w, errors.New("invalid CSRF token"), http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,209 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"strings"
+)
+
+type Approvals struct{}
+
+// GetAll lists the current user's approval requests, or those of all users for admins
+func (a *Approvals) GetAll(w http.ResponseWriter, r *http.Request) {
+	userID := utils.GetAuthUserID(r)
+	if middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+		userID = r.URL.Query().Get("user_id")
+	}
+
+	status := schema.ApprovalStatus(r.URL.Query().Get("status"))
+	utils.ResponseSuccess(w, utils.DB.ListApprovals(userID, status))
+}
+
+// Approve grants a pending request and executes the operation on behalf of the requester
+func (a *Approvals) Approve(w http.ResponseWriter, r *http.Request) {
+	if !a.checkSessionAuth(w, r) {
+		return
+	}
+
+	approval, err := utils.DB.GrantApproval(r.PathValue("id"), utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	result, execErr := a.execute(approval)
+	approval, err = utils.DB.CompleteApproval(approval.ID, result, execErr)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	recordApprovalAudit(r, schema.AuditApprovalGranted, approval,
+		fmt.Sprintf("%s: %s", approval.Summary, approval.Status))
+	utils.ResponseSuccess(w, approval)
+}
+
+// Deny rejects a pending request
+func (a *Approvals) Deny(w http.ResponseWriter, r *http.Request) {
+	if !a.checkSessionAuth(w, r) {
+		return
+	}
+
+	approval, err := utils.DB.DenyApproval(r.PathValue("id"), utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	recordApprovalAudit(r, schema.AuditApprovalDenied, approval, approval.Summary)
+	utils.ResponseSuccess(w, approval)
+}
+
+// Delete withdraws a pending request
+func (a *Approvals) Delete(w http.ResponseWriter, r *http.Request) {
+	approval, err := utils.DB.GetApproval(r.PathValue("id"))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Users may withdraw their own requests, admins may cancel any request
+	if approval.RequestedBy != utils.GetAuthUserID(r) && !middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+		return
+	}
+
+	approval, err = utils.DB.CancelApproval(approval.ID, utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	recordApprovalAudit(r, schema.AuditApprovalCancelled, approval, approval.Summary)
+	utils.ResponseSuccess(w, approval)
+}
+
+// execute runs an approved operation. The requester must still be allowed to
+// perform it, since their access may have changed while the request was pending.
+func (a *Approvals) execute(approval *schema.ApprovalRequest) (string, error) {
+	requester, err := utils.DB.GetUser(approval.RequestedBy)
+	if err != nil {
+		return "", errors.New("requester no longer exists")
+	}
+	if !requester.Enabled {
+		return "", errors.New("requester is disabled")
+	}
+
+	if approval.Operation == schema.ApprovalOperationDeleteDirectory {
+		return a.executeDeleteDirectory(approval, requester)
+	}
+	return a.executeGarage(approval, requester)
+}
+
+func (a *Approvals) executeDeleteDirectory(approval *schema.ApprovalRequest, requester *schema.User) (string, error) {
+	path, _ := strings.CutPrefix(approval.Path, "/browse/")
+	bucket, prefix, _ := strings.Cut(path, "/")
+
+	bucketID, err := getBucketID(bucket)
+	if err != nil {
+		return "", err
+	}
+	if !utils.DB.CanAccessBucket(requester, bucketID) {
+		return "", utils.ErrOutsideTenant
+	}
+	if !utils.DB.GetBucketAccess(requester, bucketID, prefix).Access.Allows(schema.BucketAccessWrite) {
+		return "", errors.New("requester no longer has write access to the directory")
+	}
+
+	browse := &Browse{}
+	res, err := browse.deleteDirectory(requester, bucket, prefix)
+	if err != nil {
+		return "", err
+	}
+	if res == nil {
+		return "directory is empty", nil
+	}
+	return fmt.Sprintf("deleted %d objects", len(res.Deleted)), nil
+}
+
+func (a *Approvals) executeGarage(approval *schema.ApprovalRequest, requester *schema.User) (string, error) {
+	if !utils.DB.HasPermission(requester, approval.Permission) {
+		return "", fmt.Errorf("requester no longer has the %s permission", approval.Permission)
+	}
+
+	url := approval.Path
+	if approval.Query != "" {
+		url += "?" + approval.Query
+	}
+
+	req, err := http.NewRequest(approval.Method, url, strings.NewReader(approval.Body))
+	if err != nil {
+		return "", err
+	}
+	if err := checkProxyScope(req, requester, approval.Operation); err != nil {
+		return "", err
+	}
+
+	options := &utils.FetchOptions{Method: approval.Method}
+	if strings.TrimSpace(approval.Body) != "" {
+		options.Body = json.RawMessage(approval.Body)
+	}
+
+	body, err := utils.Garage.Fetch(url, options)
+	if err != nil {
+		return "", err
+	}
+
+	// Track ownership of created and deleted resources as the proxy does
+	res := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    req,
+	}
+	if err := scopeProxyResponse(res, requester, approval.Operation); err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// checkSessionAuth rejects approval reviews made with an access token
+func (a *Approvals) checkSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if auth := utils.GetAuth(r); auth != nil && auth.Method == utils.AuthMethodToken {
+		utils.ResponseErrorStatus(w, errors.New("access tokens cannot review approval requests"), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// queueApproval stores an operation of the current user for approval by a second admin
+func queueApproval(w http.ResponseWriter, r *http.Request, approval *schema.ApprovalRequest) {
+	approval.RequestedBy = utils.GetAuthUserID(r)
+
+	created, err := utils.DB.RequestApproval(approval)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	recordApprovalAudit(r, schema.AuditApprovalRequested, created, created.Summary)
+	utils.ResponseSuccessStatus(w, created, http.StatusAccepted)
+}
+
+func recordApprovalAudit(r *http.Request, eventType schema.AuditEventType, approval *schema.ApprovalRequest, message string) {
+	event := utils.NewAuditEvent(r, eventType)
+	event.UserID = approval.RequestedBy
+	if user, err := utils.DB.GetUser(approval.RequestedBy); err == nil {
+		event.Username = user.Username
+	}
+	event.Message = message
+	utils.DB.RecordAudit(event)
+}
@@ -0,0 +1,28 @@
+package router
+
+import (
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"strconv"
+)
+
+type Audit struct{}
+
+func (a *Audit) GetAll(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	events := utils.DB.ListAudit(&schema.AuditQuery{
+		Type:     schema.AuditEventType(params.Get("type")),
+		UserID:   params.Get("user_id"),
+		Username: params.Get("username"),
+		IP:       params.Get("ip"),
+		Limit:    limit,
+	})
+
+	utils.ResponseSuccess(w, events)
+}
@@ -2,93 +2,159 @@ package router
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
+	"log"
+	"math"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	twoFactorChallengeTTL         = 5 * time.Minute
+	twoFactorChallengeMaxAttempts = 5
 )
 
 type Auth struct{}
 
+// twoFactorChallenge tracks a login waiting for its second factor
+type twoFactorChallenge struct {
+	UserID   string
+	Attempts int
+}
+
 func (c *Auth) Login(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("Login attempt started")
 	var body schema.LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		fmt.Printf("Failed to decode request body: %v\n", err)
 		utils.ResponseError(w, err)
 		return
 	}
-	fmt.Printf("Login request for user: %s\n", body.Username)
+
+	if !c.checkLoginAllowed(w, r, body.Username) {
+		return
+	}
 
 	// Authenticate user
-	user, err := utils.DB.AuthenticateUser(body.Username, body.Password)
+	user, err := utils.AuthenticateCredentials(body.Username, body.Password)
 	if err != nil {
-		fmt.Printf("Authentication failed: %v\n", err)
+		utils.LoginGuard.RecordFailure(r, body.Username, err)
 		utils.ResponseErrorStatus(w, err, 401)
 		return
 	}
-	fmt.Println("User authenticated successfully")
 
-	// Create session
-	session, err := utils.DB.CreateSession(user.ID)
-	if err != nil {
-		fmt.Printf("Failed to create session: %v\n", err)
+	// Passwords set before the policy was tightened have to be rotated
+	if user.AuthSource == "" || user.AuthSource == schema.AuthSourceLocal {
+		if err := utils.PasswordPolicy.Validate(body.Password, user.Username); err != nil && !user.MustChangePassword {
+			utils.DB.SetMustChangePassword(user.ID, true)
+		}
+	}
+
+	if user.TwoFactorEnabled {
+		// Accept the code inline so API clients can log in with a single request
+		if body.Code != "" {
+			if err := utils.DB.VerifyTwoFactor(user.ID, body.Code); err != nil {
+				utils.LoginGuard.RecordFailure(r, user.Username, err)
+				utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+				return
+			}
+		} else {
+			c.sendTwoFactorChallenge(w, user)
+			return
+		}
+	}
+
+	c.startSession(w, r, user)
+}
+
+// LoginTwoFactor completes a login started with a password by verifying the second factor
+func (c *Auth) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var body schema.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		utils.ResponseError(w, err)
 		return
 	}
-	fmt.Println("Session created successfully")
 
-	// Set session in cookie/session store
-	utils.Session.Set(r, "user_id", user.ID)
-	utils.Session.Set(r, "session_id", session.ID)
-	utils.Session.Set(r, "authenticated", true)
-	fmt.Println("Session data set")
+	cacheKey := "2fa:" + body.TwoFactorToken
+	challenge, ok := utils.Cache.Get(cacheKey).(*twoFactorChallenge)
+	if body.TwoFactorToken == "" || !ok {
+		utils.ResponseErrorStatus(w, errors.New("two-factor challenge expired"), http.StatusUnauthorized)
+		return
+	}
 
-	response := schema.LoginResponse{
-		User:      *user,
-		Token:     session.Token,
-		ExpiresAt: session.ExpiresAt,
+	user, err := utils.DB.GetUser(challenge.UserID)
+	if err != nil || !user.Enabled {
+		utils.ResponseErrorStatus(w, errors.New("unauthorized"), http.StatusUnauthorized)
+		return
 	}
 
-	fmt.Println("Sending login response")
-	utils.ResponseSuccess(w, response)
+	if !c.checkLoginAllowed(w, r, user.Username) {
+		return
+	}
+
+	if err := utils.DB.VerifyTwoFactor(user.ID, body.Code); err != nil {
+		utils.LoginGuard.RecordFailure(r, user.Username, err)
+		challenge.Attempts++
+		if challenge.Attempts >= twoFactorChallengeMaxAttempts {
+			utils.Cache.Delete(cacheKey)
+		}
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+	utils.Cache.Delete(cacheKey)
+
+	c.startSession(w, r, user)
+}
+
+// GetProviders lists the login methods available besides username and password
+func (c *Auth) GetProviders(w http.ResponseWriter, r *http.Request) {
+	providers := schema.AuthProvidersResponse{
+		OIDC: schema.AuthProvider{
+			Enabled: utils.OIDC.Enabled(),
+			Name:    utils.OIDC.GetProviderName(),
+		},
+	}
+
+	if providers.OIDC.Enabled {
+		providers.OIDC.LoginURL = os.Getenv("BASE_PATH") + "/api/auth/oidc/login"
+	}
+
+	// Users are logged in by the reverse proxy, so the password form is not needed
+	if utils.ForwardAuth.Enabled() {
+		providers.ForwardAuth = schema.AuthProvider{
+			Enabled:   true,
+			LogoutURL: utils.GetEnv("FORWARD_AUTH_LOGOUT_URL", ""),
+		}
+	}
+
+	utils.ResponseSuccess(w, providers)
 }
 
 func (c *Auth) Logout(w http.ResponseWriter, r *http.Request) {
-	// Get session ID from session store
-	sessionID := utils.Session.Get(r, "session_id")
-	if sessionID != nil {
-		// Delete session from database
-		utils.DB.DeleteSession(sessionID.(string))
+	// Revoke the session used to authenticate this request
+	if auth := utils.GetAuth(r); auth != nil && auth.SessionID != "" {
+		utils.DB.DeleteSession(auth.SessionID)
 	}
 
-	utils.Session.Clear(r)
+	utils.Session.Destroy(r)
 	utils.ResponseSuccess(w, map[string]bool{"success": true})
 }
 
 func (c *Auth) GetStatus(w http.ResponseWriter, r *http.Request) {
-	fmt.Println("GetStatus: Checking authentication status")
 	enabled := true // Authentication is always enabled now
 	authenticated := false
 	var user *schema.User
 
-	authSession := utils.Session.Get(r, "authenticated")
-	userID := utils.Session.Get(r, "user_id")
-
-	fmt.Printf("GetStatus: authSession=%v, userID=%v\n", authSession, userID)
+	userID := utils.GetAuthUserID(r)
 
-	if authSession != nil && authSession.(bool) && userID != nil {
+	if userID != "" {
 		authenticated = true
-		fmt.Println("GetStatus: User is authenticated")
 		// Get user details
-		if u, err := utils.DB.GetUser(userID.(string)); err == nil {
+		if u, err := utils.DB.GetUser(userID); err == nil {
 			user = u
-			fmt.Printf("GetStatus: User found: %s\n", user.Username)
-		} else {
-			fmt.Printf("GetStatus: Failed to get user: %v\n", err)
 		}
-	} else {
-		fmt.Println("GetStatus: User is not authenticated")
 	}
 
 	response := schema.AuthStatusResponse{
@@ -97,5 +163,148 @@ func (c *Auth) GetStatus(w http.ResponseWriter, r *http.Request) {
 		User:          user,
 	}
 
+	if auth := utils.GetAuth(r); user != nil && auth != nil && auth.ImpersonatorID != "" {
+		// Flag the impersonation so the UI can show who is really behind the session
+		impersonator, err := utils.DB.GetUser(auth.ImpersonatorID)
+		session, sessionErr := utils.DB.GetSession(auth.SessionID)
+		if err == nil && sessionErr == nil && session.Impersonation != nil {
+			response.Impersonation = &schema.ImpersonationStatus{
+				Impersonator: *impersonator,
+				ExpiresAt:    session.Impersonation.ExpiresAt,
+			}
+		}
+	} else if user != nil {
+		response.TwoFactorSetupRequired = utils.DB.RequiresTwoFactorSetup(user)
+		response.PasswordChangeRequired = utils.DB.RequiresPasswordChange(user)
+	}
+
+	if user != nil {
+		if token, err := utils.EnsureCSRFToken(w, r); err == nil {
+			response.CSRFToken = token
+		}
+	}
+
 	utils.ResponseSuccess(w, response)
-}
\ No newline at end of file
+}
+
+// ChangePassword lets a local user replace their own password
+func (c *Auth) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	auth := utils.GetAuth(r)
+	if auth == nil || auth.Method == utils.AuthMethodToken {
+		utils.ResponseErrorStatus(w, errors.New("access tokens cannot change passwords"), http.StatusForbidden)
+		return
+	}
+
+	var body schema.ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := utils.DB.GetUser(auth.UserID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	if !c.checkLoginAllowed(w, r, user.Username) {
+		return
+	}
+
+	err = utils.DB.ChangePassword(user.ID, body.CurrentPassword, body.NewPassword)
+	if errors.Is(err, utils.ErrInvalidCurrentPassword) {
+		utils.LoginGuard.RecordFailure(r, user.Username, err)
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Log out every other device that may know the old password
+	if err := utils.DB.DeleteOtherSessions(user.ID, auth.SessionID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// checkLoginAllowed rejects login attempts from locked out clients and accounts
+func (c *Auth) checkLoginAllowed(w http.ResponseWriter, r *http.Request, username string) bool {
+	retryAfter, allowed := utils.LoginGuard.Check(utils.GetClientIP(r), username)
+	if allowed {
+		return true
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditLoginBlocked)
+	event.Username = username
+	utils.DB.RecordAudit(event)
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	utils.ResponseErrorStatus(w, utils.ErrLoginLocked, http.StatusTooManyRequests)
+	return false
+}
+
+// sendTwoFactorChallenge asks the client to complete the login with a second factor
+func (c *Auth) sendTwoFactorChallenge(w http.ResponseWriter, user *schema.User) {
+	token, err := utils.GenerateToken()
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.Cache.Set("2fa:"+token, &twoFactorChallenge{UserID: user.ID}, twoFactorChallengeTTL)
+
+	utils.ResponseSuccess(w, schema.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		TwoFactorToken:    token,
+		ExpiresAt:         time.Now().Add(twoFactorChallengeTTL),
+	})
+}
+
+// startSession creates a session for an authenticated user and sends the login response
+func (c *Auth) startSession(w http.ResponseWriter, r *http.Request, user *schema.User) {
+	session, err := createLoginSession(w, r, user)
+	if err != nil {
+		log.Printf("Failed to create session for %s: %v", user.Username, err)
+		utils.ResponseError(w, err)
+		return
+	}
+
+	csrfToken, _ := utils.Session.Get(r, "csrf_token").(string)
+
+	response := schema.LoginResponse{
+		User:      *user,
+		Token:     session.Token,
+		CSRFToken: csrfToken,
+		ExpiresAt: session.ExpiresAt,
+	}
+
+	utils.ResponseSuccess(w, response)
+}
+
+// createLoginSession creates a session for an authenticated user and stores it in the cookie session
+func createLoginSession(w http.ResponseWriter, r *http.Request, user *schema.User) (*schema.Session, error) {
+	// Create session
+	session, err := utils.DB.CreateSession(user.ID, utils.GetClientIP(r), r.UserAgent())
+	if err != nil {
+		return nil, err
+	}
+
+	// Set session in cookie/session store
+	if err := utils.Session.RenewToken(r); err != nil {
+		return nil, err
+	}
+	utils.Session.Set(r, "user_id", user.ID)
+	utils.Session.Set(r, "session_id", session.ID)
+	utils.Session.Set(r, "authenticated", true)
+	if _, err := utils.IssueCSRFToken(w, r); err != nil {
+		return nil, err
+	}
+
+	utils.LoginGuard.RecordSuccess(r, user)
+
+	return session, nil
+}
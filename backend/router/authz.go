@@ -0,0 +1,179 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"strings"
+)
+
+type Authz struct{}
+
+// Explain reports whether a user may perform an action on a resource, and the role,
+// groups, tenant scope and bucket grant the decision is based on. The action is either
+// a permission or one of the object actions checked by the object browser. The resource
+// is optional for permissions and one of "bucket:{alias}[/{key}]", "key:{id}" or "user:{id}".
+// With the ID of one of the user's access tokens, the token's scopes are applied too.
+//
+// The decision is made by the same checks as the API routes; the role and group sources
+// only explain it.
+func (a *Authz) Explain(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	action := query.Get("action")
+	resource := query.Get("resource")
+
+	userID := query.Get("user")
+	if user, err := utils.DB.GetUserByUsername(userID); err == nil {
+		userID = user.ID
+	}
+	user, ok := getScopedUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	auth := &utils.AuthInfo{UserID: user.ID, Method: utils.AuthMethodSession, User: user}
+	if tokenID := query.Get("token"); tokenID != "" {
+		accessToken, err := utils.DB.GetAccessToken(tokenID)
+		if err != nil || accessToken.UserID != user.ID {
+			utils.ResponseErrorStatus(w, errors.New("access token not found"), http.StatusNotFound)
+			return
+		}
+		auth.Method = utils.AuthMethodToken
+		auth.Scopes = accessToken.Scopes
+	}
+	req := utils.WithAuth(r, auth)
+
+	explanation := a.describeUser(user)
+	explanation.Action = action
+	explanation.Resource = resource
+
+	var err error
+	switch action {
+	case schema.AuthzActionReadObjects:
+		err = a.explainObjectAccess(explanation, req, resource, schema.BucketAccessRead)
+	case schema.AuthzActionWriteObjects:
+		err = a.explainObjectAccess(explanation, req, resource, schema.BucketAccessWrite)
+	default:
+		err = a.explainPermission(explanation, req, schema.Permission(action), resource)
+	}
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Checked by the auth middleware before any route
+	if auth.Method != utils.AuthMethodToken && utils.DB.RequiresPasswordChange(user) {
+		explanation.Allowed = false
+		explanation.Reason = "password change required"
+	}
+	if !user.Enabled {
+		explanation.Allowed = false
+		explanation.Reason = "user is disabled"
+	}
+
+	utils.ResponseSuccess(w, explanation)
+}
+
+// describeUser collects the role, groups and tenant scope of a user
+func (a *Authz) describeUser(user *schema.User) *schema.AuthzExplanation {
+	explanation := &schema.AuthzExplanation{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Role:      user.Role,
+		Groups:    []schema.AuthzGroup{},
+		TenantID:  utils.DB.GetUserTenantID(user),
+		GrantedBy: []string{},
+	}
+
+	if user.Elevation.IsActive() {
+		role := user.Elevation.Role
+		explanation.ElevatedRole = &role
+	}
+
+	for _, group := range utils.DB.GetUserGroups(user.ID) {
+		explanation.Groups = append(explanation.Groups, schema.AuthzGroup{
+			ID:       group.ID,
+			Name:     group.Name,
+			Roles:    group.Roles,
+			TenantID: group.TenantID,
+		})
+	}
+
+	_, explanation.TenantScoped = utils.DB.GetUserScope(user)
+	return explanation
+}
+
+func (a *Authz) explainPermission(explanation *schema.AuthzExplanation, r *http.Request, permission schema.Permission, resource string) error {
+	if !permission.IsValid() {
+		return fmt.Errorf("unknown action: %s", permission)
+	}
+
+	var kind, id string
+	if resource != "" {
+		kind, id, _ = strings.Cut(resource, ":")
+		if kind != "bucket" && kind != "key" && kind != "user" {
+			return fmt.Errorf("unknown resource: %s", resource)
+		}
+	}
+
+	user := utils.GetAuth(r).User
+	explanation.GrantedBy = utils.DB.GetPermissionSources(user, permission)
+
+	if !middleware.HasPermission(r, permission) {
+		if !utils.DB.HasPermission(user, permission) {
+			explanation.Reason = fmt.Sprintf("no role or group grants %s", permission)
+		} else {
+			explanation.Reason = fmt.Sprintf("the access token is not scoped for %s", permission)
+		}
+		return nil
+	}
+
+	// Missing resources and those of other tenants get the same answer
+	inScope := true
+	switch kind {
+	case "bucket":
+		bucket, _, _ := strings.Cut(id, "/")
+		bucketID, err := getBucketID(bucket)
+		inScope = err == nil && utils.DB.CanAccessBucket(user, bucketID)
+	case "key":
+		inScope = utils.DB.CanAccessKey(user, id)
+	case "user":
+		target, err := utils.DB.GetUser(id)
+		inScope = err == nil && utils.DB.CanAccessUser(user, target)
+	}
+	if !inScope {
+		explanation.Reason = fmt.Sprintf("the %s does not exist or belongs to another tenant", kind)
+		return nil
+	}
+
+	explanation.Allowed = true
+	explanation.Reason = fmt.Sprintf("%s is granted by %s", permission, strings.Join(explanation.GrantedBy, ", "))
+	return nil
+}
+
+func (a *Authz) explainObjectAccess(explanation *schema.AuthzExplanation, r *http.Request, resource string, required schema.BucketAccess) error {
+	path, ok := strings.CutPrefix(resource, "bucket:")
+	if !ok {
+		return errors.New(`object actions require a "bucket:{alias}[/{key}]" resource`)
+	}
+
+	bucket, key, _ := strings.Cut(path, "/")
+	decision, _, err := authorizeBucketAccess(r, bucket, key, required)
+	explanation.BucketAccess = decision
+	explanation.Allowed = err == nil
+
+	switch {
+	case errors.Is(err, errBucketNotFound):
+		explanation.Reason = "the bucket does not exist or belongs to another tenant"
+	case decision == nil:
+		explanation.Reason = err.Error()
+	case decision.Grant != nil && decision.Grant.Prefix != "":
+		explanation.Reason = fmt.Sprintf("%s on prefix %q gives %s access", decision.Reason, decision.Grant.Prefix, decision.Access)
+	default:
+		explanation.Reason = fmt.Sprintf("%s gives %s access", decision.Reason, decision.Access)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"encoding/json"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestAuthzExplain(t *testing.T) {
+	acme, globex := "acme", "globex"
+	admin := &schema.User{ID: "authz-admin", Username: "authz-admin", Role: schema.RoleAdmin, Enabled: true}
+	member := &schema.User{ID: "authz-member", Username: "authz-member", Role: schema.RoleUser, TenantID: &acme, Enabled: true}
+	rotating := &schema.User{ID: "authz-rotating", Username: "authz-rotating", Role: schema.RoleUser, Enabled: true, MustChangePassword: true}
+	outsider := &schema.User{ID: "authz-outsider", Username: "authz-outsider", Role: schema.RoleUser, TenantID: &globex, Enabled: true}
+	addTestUsers(t, []string{acme, globex}, admin, member, rotating, outsider)
+
+	utils.DB.AccessTokens["authz-token"] = &schema.AccessToken{
+		ID:     "authz-token",
+		UserID: member.ID,
+		Scopes: []schema.Permission{schema.PermissionReadKeys},
+	}
+	defer delete(utils.DB.AccessTokens, "authz-token")
+
+	explain := func(t *testing.T, params url.Values) *schema.AuthzExplanation {
+		r := httptest.NewRequest(http.MethodGet, "/authz/explain?"+params.Encode(), nil)
+		w := httptest.NewRecorder()
+		(&Authz{}).Explain(w, withTestAuth(r, admin))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", w.Code, w.Body.String())
+		}
+
+		var res struct{ Data schema.AuthzExplanation }
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		return &res.Data
+	}
+
+	tests := []struct {
+		name    string
+		params  url.Values
+		allowed bool
+		reason  string
+	}{
+		{"granted", url.Values{"user": {member.ID}, "action": {"write_keys"}}, true, "write_keys is granted by role user"},
+		{"not granted", url.Values{"user": {member.ID}, "action": {"read_users"}}, false, "no role or group grants read_users"},
+		{"token scope", url.Values{"user": {member.ID}, "action": {"write_keys"}, "token": {"authz-token"}}, false, "the access token is not scoped for write_keys"},
+		{"token scope granted", url.Values{"user": {member.ID}, "action": {"read_keys"}, "token": {"authz-token"}}, true, "read_keys is granted by role user"},
+		{"password change", url.Values{"user": {rotating.ID}, "action": {"read_keys"}}, false, "password change required"},
+		{"other tenant", url.Values{"user": {member.ID}, "action": {"write_keys"}, "resource": {"user:" + outsider.ID}}, false, "the user does not exist or belongs to another tenant"},
+		{"missing resource", url.Values{"user": {member.ID}, "action": {"write_keys"}, "resource": {"user:missing"}}, false, "the user does not exist or belongs to another tenant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			explanation := explain(t, tt.params)
+			if explanation.Allowed != tt.allowed || explanation.Reason != tt.reason {
+				t.Errorf("allowed = %v, reason = %q, want %v, %q", explanation.Allowed, explanation.Reason, tt.allowed, tt.reason)
+			}
+		})
+	}
+}
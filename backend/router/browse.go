@@ -9,6 +9,7 @@ import (
 	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +23,10 @@ import (
 
 type Browse struct{}
 
+var errDirectoryNotWritable = errors.New("directory contains objects you cannot delete")
+
+var errBucketNotFound = errors.New("bucket not found")
+
 func (b *Browse) GetObjects(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query()
 	bucket := r.PathValue("bucket")
@@ -33,6 +38,10 @@ func (b *Browse) GetObjects(w http.ResponseWriter, r *http.Request) {
 		limit = 100
 	}
 
+	if !b.checkBucketAccess(w, r, bucket, prefix, schema.BucketAccessRead) {
+		return
+	}
+
 	client, err := getS3Client(bucket)
 	if err != nil {
 		utils.ResponseError(w, err)
@@ -88,6 +97,10 @@ func (b *Browse) GetOneObject(w http.ResponseWriter, r *http.Request) {
 	thumbnail := queryParams.Get("thumb") == "1"
 	download := queryParams.Get("dl") == "1"
 
+	if !b.checkBucketAccess(w, r, bucket, key, schema.BucketAccessRead) {
+		return
+	}
+
 	client, err := getS3Client(bucket)
 	if err != nil {
 		utils.ResponseError(w, err)
@@ -184,6 +197,10 @@ func (b *Browse) PutObject(w http.ResponseWriter, r *http.Request) {
 		defer file.Close()
 	}
 
+	if !b.checkBucketAccess(w, r, bucket, key, schema.BucketAccessWrite) {
+		return
+	}
+
 	client, err := getS3Client(bucket)
 	if err != nil {
 		utils.ResponseError(w, err)
@@ -220,49 +237,41 @@ func (b *Browse) DeleteObject(w http.ResponseWriter, r *http.Request) {
 	recursive := r.URL.Query().Get("recursive") == "true"
 	isDirectory := strings.HasSuffix(key, "/")
 
-	client, err := getS3Client(bucket)
-	if err != nil {
-		utils.ResponseError(w, err)
+	if !b.checkBucketAccess(w, r, bucket, key, schema.BucketAccessWrite) {
 		return
 	}
 
 	// Delete directory and its content
 	if isDirectory && recursive {
-		objects, err := client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
-			Bucket: aws.String(bucket),
-			Prefix: aws.String(key),
-		})
-
-		if err != nil {
-			utils.ResponseError(w, err)
+		if utils.DB.RequiresApproval(schema.ApprovalOperationDeleteDirectory) {
+			queueApproval(w, r, &schema.ApprovalRequest{
+				Operation: schema.ApprovalOperationDeleteDirectory,
+				Method:    r.Method,
+				Path:      r.URL.Path,
+				Query:     "recursive=true",
+				Summary:   fmt.Sprintf("%s %s/%s", schema.ApprovalOperationDeleteDirectory, bucket, key),
+			})
 			return
 		}
 
-		if len(objects.Contents) == 0 {
-			utils.ResponseSuccess(w, true)
+		user, err := utils.GetAuthUser(r)
+		if err != nil {
+			utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
 			return
 		}
 
-		keys := make([]types.ObjectIdentifier, 0, len(objects.Contents))
-
-		for _, object := range objects.Contents {
-			keys = append(keys, types.ObjectIdentifier{
-				Key: object.Key,
-			})
+		res, err := b.deleteDirectory(user, bucket, key)
+		if errors.Is(err, errDirectoryNotWritable) {
+			utils.ResponseErrorStatus(w, err, http.StatusForbidden)
+			return
 		}
-
-		res, err := client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
-			Bucket: aws.String(bucket),
-			Delete: &types.Delete{Objects: keys},
-		})
-
 		if err != nil {
-			utils.ResponseError(w, fmt.Errorf("cannot delete object: %w", err))
+			utils.ResponseError(w, err)
 			return
 		}
 
-		if len(res.Errors) > 0 {
-			utils.ResponseError(w, fmt.Errorf("cannot delete object: %v", res.Errors[0]))
+		if res == nil {
+			utils.ResponseSuccess(w, true)
 			return
 		}
 
@@ -270,6 +279,12 @@ func (b *Browse) DeleteObject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	client, err := getS3Client(bucket)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
 	// Delete single object
 	res, err := client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
 		Bucket: aws.String(bucket),
@@ -284,6 +299,117 @@ func (b *Browse) DeleteObject(w http.ResponseWriter, r *http.Request) {
 	utils.ResponseSuccess(w, res)
 }
 
+// deleteDirectory deletes the objects under a prefix. It returns nil when the prefix is
+// empty, and deletes nothing when a narrower grant denies the user write access to any
+// of the objects.
+func (b *Browse) deleteDirectory(user *schema.User, bucket, prefix string) (*s3.DeleteObjectsOutput, error) {
+	bucketID, err := getBucketID(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := getS3Client(bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	objects, err := client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(prefix),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects.Contents) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]types.ObjectIdentifier, 0, len(objects.Contents))
+
+	for _, object := range objects.Contents {
+		if !utils.DB.GetBucketAccess(user, bucketID, aws.ToString(object.Key)).Access.Allows(schema.BucketAccessWrite) {
+			return nil, errDirectoryNotWritable
+		}
+		keys = append(keys, types.ObjectIdentifier{
+			Key: object.Key,
+		})
+	}
+
+	res, err := client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &types.Delete{Objects: keys},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot delete object: %w", err)
+	}
+
+	if len(res.Errors) > 0 {
+		return nil, fmt.Errorf("cannot delete object: %v", res.Errors[0])
+	}
+
+	return res, nil
+}
+
+// checkBucketAccess rejects buckets outside the tenant of the current user and
+// keys the user's bucket grants or permissions do not give the required access to
+func (b *Browse) checkBucketAccess(w http.ResponseWriter, r *http.Request, bucket, key string, required schema.BucketAccess) bool {
+	if _, status, err := authorizeBucketAccess(r, bucket, key, required); err != nil {
+		utils.ResponseErrorStatus(w, err, status)
+		return false
+	}
+	return true
+}
+
+// authorizeBucketAccess decides whether the user of a request may access a key of a
+// bucket, returning the grant decision or the status and error to deny the request
+// with. Buckets of other tenants are reported as missing, so they cannot be probed.
+func authorizeBucketAccess(r *http.Request, bucket, key string, required schema.BucketAccess) (*schema.BucketAccessDecision, int, error) {
+	user, err := utils.GetAuthUser(r)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	bucketID, err := getBucketID(bucket)
+	if err != nil || !utils.DB.CanAccessBucket(user, bucketID) {
+		return nil, http.StatusNotFound, errBucketNotFound
+	}
+
+	// Access tokens also need the scope matching the required access
+	scope := schema.PermissionReadBuckets
+	if required == schema.BucketAccessWrite {
+		scope = schema.PermissionWriteBuckets
+	}
+	if !utils.GetAuth(r).AllowsScope(scope) {
+		return nil, http.StatusForbidden, fmt.Errorf("access token is not scoped for %s", scope)
+	}
+
+	decision := utils.DB.GetBucketAccess(user, bucketID, key)
+	if !decision.Access.Allows(required) {
+		return &decision, http.StatusForbidden, fmt.Errorf("%s access to %s/%s denied", required, bucket, key)
+	}
+
+	return &decision, http.StatusOK, nil
+}
+
+// getBucketID resolves the global alias of a bucket to its ID. The result decides
+// tenant and grant checks, so it is not cached: an alias may move to another bucket.
+func getBucketID(bucket string) (string, error) {
+	body, err := utils.Garage.Fetch("/v2/GetBucketInfo?globalAlias="+url.QueryEscape(bucket), &utils.FetchOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	var bucketData schema.Bucket
+	if err := json.Unmarshal(body, &bucketData); err != nil {
+		return "", err
+	}
+
+	return bucketData.ID, nil
+}
+
 func getBucketCredentials(bucket string) (aws.CredentialsProvider, error) {
 	cacheKey := fmt.Sprintf("key:%s", bucket)
 	cacheData := utils.Cache.Get(cacheKey)
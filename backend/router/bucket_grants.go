@@ -0,0 +1,156 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+)
+
+type BucketGrants struct{}
+
+var errOwnBucketGrant = errors.New("cannot manage bucket grants of yourself or your groups")
+
+// GetAll lists the grants of a bucket
+func (bg *BucketGrants) GetAll(w http.ResponseWriter, r *http.Request) {
+	bucketID := r.PathValue("id")
+	if !bg.checkBucket(w, r, bucketID) {
+		return
+	}
+
+	utils.ResponseSuccess(w, utils.DB.ListBucketGrants(bucketID))
+}
+
+// Create grants a user or group access to the objects of a bucket
+func (bg *BucketGrants) Create(w http.ResponseWriter, r *http.Request) {
+	bucketID := r.PathValue("id")
+	if !bg.checkBucket(w, r, bucketID) {
+		return
+	}
+
+	var req schema.CreateBucketGrantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if !bg.checkSubject(w, r, &req) {
+		return
+	}
+	if bg.isOwnGrant(r, req.UserID, req.GroupID) {
+		utils.ResponseErrorStatus(w, errOwnBucketGrant, http.StatusForbidden)
+		return
+	}
+
+	grant, err := utils.DB.SetBucketGrant(bucketID, &req, utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	bg.recordAudit(r, schema.AuditBucketGrantSet, grant)
+	utils.ResponseSuccess(w, grant)
+}
+
+// Delete revokes a bucket grant
+func (bg *BucketGrants) Delete(w http.ResponseWriter, r *http.Request) {
+	bucketID := r.PathValue("id")
+	if !bg.checkBucket(w, r, bucketID) {
+		return
+	}
+
+	grant, err := utils.DB.GetBucketGrant(r.PathValue("grantId"))
+	if err != nil || grant.BucketID != bucketID {
+		utils.ResponseErrorStatus(w, errors.New("grant not found"), http.StatusNotFound)
+		return
+	}
+
+	if bg.isOwnGrant(r, grant.UserID, grant.GroupID) {
+		utils.ResponseErrorStatus(w, errOwnBucketGrant, http.StatusForbidden)
+		return
+	}
+
+	if err := utils.DB.DeleteBucketGrant(grant.ID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	bg.recordAudit(r, schema.AuditBucketGrantDeleted, grant)
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// checkBucket rejects buckets outside the tenant of the current user
+func (bg *BucketGrants) checkBucket(w http.ResponseWriter, r *http.Request, bucketID string) bool {
+	user, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return false
+	}
+
+	if !utils.DB.CanAccessBucket(user, bucketID) {
+		utils.ResponseErrorStatus(w, utils.ErrOutsideTenant, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// checkSubject rejects grants to users and groups outside the tenant of the current user
+func (bg *BucketGrants) checkSubject(w http.ResponseWriter, r *http.Request, req *schema.CreateBucketGrantRequest) bool {
+	if req.UserID != nil {
+		_, ok := getScopedUser(w, r, *req.UserID)
+		return ok
+	}
+
+	if req.GroupID != nil {
+		actor, err := utils.GetAuthUser(r)
+		if err != nil {
+			utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+			return false
+		}
+
+		group, err := utils.DB.GetGroup(*req.GroupID)
+		if err != nil {
+			utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+			return false
+		}
+
+		tenantID, scoped := utils.DB.GetUserScope(actor)
+		if scoped && (group.TenantID == nil || *group.TenantID != tenantID) {
+			utils.ResponseErrorStatus(w, utils.ErrOutsideTenant, http.StatusForbidden)
+			return false
+		}
+	}
+	return true
+}
+
+// isOwnGrant checks if a grant subject is the current user or one of their groups
+func (bg *BucketGrants) isOwnGrant(r *http.Request, userID, groupID *string) bool {
+	actorID := utils.GetAuthUserID(r)
+	if userID != nil && *userID == actorID {
+		return true
+	}
+	if groupID != nil {
+		group, err := utils.DB.GetGroup(*groupID)
+		return err == nil && group.HasMember(actorID)
+	}
+	return false
+}
+
+func (bg *BucketGrants) recordAudit(r *http.Request, eventType schema.AuditEventType, grant *schema.BucketGrant) {
+	subject := "user"
+	subjectID := ""
+	if grant.UserID != nil {
+		subjectID = *grant.UserID
+	}
+	if grant.GroupID != nil {
+		subject = "group"
+		subjectID = *grant.GroupID
+	}
+
+	event := utils.NewAuditEvent(r, eventType)
+	event.Message = fmt.Sprintf("bucket %s prefix %q: %s %s access=%s",
+		grant.BucketID, grant.Prefix, subject, subjectID, grant.Access)
+	utils.DB.RecordAudit(event)
+}
@@ -11,18 +11,37 @@ import (
 type Buckets struct{}
 
 func (b *Buckets) GetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	if !utils.GetAuth(r).AllowsScope(schema.PermissionReadBuckets) {
+		utils.ResponseErrorStatus(w, fmt.Errorf("access token is not scoped for %s", schema.PermissionReadBuckets), http.StatusForbidden)
+		return
+	}
+
 	body, err := utils.Garage.Fetch("/v2/ListBuckets", &utils.FetchOptions{})
 	if err != nil {
 		utils.ResponseError(w, err)
 		return
 	}
 
-	var buckets []schema.GetBucketsRes
-	if err := json.Unmarshal(body, &buckets); err != nil {
+	var all []schema.GetBucketsRes
+	if err := json.Unmarshal(body, &all); err != nil {
 		utils.ResponseError(w, err)
 		return
 	}
 
+	// Only list the buckets of the user's tenant that the user has access to
+	buckets := make([]schema.GetBucketsRes, 0, len(all))
+	for _, bucket := range all {
+		if utils.DB.CanAccessBucket(user, bucket.ID) && utils.DB.HasBucketAccess(user, bucket.ID) {
+			buckets = append(buckets, bucket)
+		}
+	}
+
 	ch := make(chan schema.Bucket, len(buckets))
 
 	for _, bucket := range buckets {
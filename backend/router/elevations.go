@@ -0,0 +1,129 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+)
+
+type Elevations struct{}
+
+// GetAll lists the current user's elevation requests, or those of all users for admins
+func (e *Elevations) GetAll(w http.ResponseWriter, r *http.Request) {
+	userID := utils.GetAuthUserID(r)
+	if middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+		userID = r.URL.Query().Get("user_id")
+	}
+
+	status := schema.ElevationStatus(r.URL.Query().Get("status"))
+	utils.ResponseSuccess(w, utils.DB.ListElevations(userID, status))
+}
+
+// Create requests a temporary role elevation for the current user
+func (e *Elevations) Create(w http.ResponseWriter, r *http.Request) {
+	if !e.checkSessionAuth(w, r) {
+		return
+	}
+
+	var req schema.CreateElevationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	elevation, err := utils.DB.RequestElevation(utils.GetAuthUserID(r), &req)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	e.recordAudit(r, schema.AuditElevationRequested, elevation,
+		fmt.Sprintf("%s for %s: %s", elevation.Role, elevation.Duration, elevation.Reason))
+	utils.ResponseSuccess(w, elevation)
+}
+
+// Approve grants a pending elevation request
+func (e *Elevations) Approve(w http.ResponseWriter, r *http.Request) {
+	if !e.checkSessionAuth(w, r) {
+		return
+	}
+
+	elevation, err := utils.DB.ApproveElevation(r.PathValue("id"), utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	e.recordAudit(r, schema.AuditElevationGranted, elevation,
+		fmt.Sprintf("%s until %s", elevation.Role, elevation.ExpiresAt.Format("2006-01-02 15:04:05 MST")))
+	utils.ResponseSuccess(w, elevation)
+}
+
+// Deny rejects a pending elevation request
+func (e *Elevations) Deny(w http.ResponseWriter, r *http.Request) {
+	if !e.checkSessionAuth(w, r) {
+		return
+	}
+
+	elevation, err := utils.DB.DenyElevation(r.PathValue("id"), utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	e.recordAudit(r, schema.AuditElevationDenied, elevation, string(elevation.Role))
+	utils.ResponseSuccess(w, elevation)
+}
+
+// Delete withdraws a pending request or ends an active elevation early
+func (e *Elevations) Delete(w http.ResponseWriter, r *http.Request) {
+	elevationID := r.PathValue("id")
+	if elevationID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	elevation, err := utils.DB.GetElevation(elevationID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Users may drop their own elevations, admins may revoke any elevation
+	if elevation.UserID != utils.GetAuthUserID(r) && !middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+		return
+	}
+
+	elevation, err = utils.DB.RevokeElevation(elevationID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	e.recordAudit(r, schema.AuditElevationRevoked, elevation, string(elevation.Role))
+	utils.ResponseSuccess(w, elevation)
+}
+
+func (e *Elevations) recordAudit(r *http.Request, eventType schema.AuditEventType, elevation *schema.ElevationRequest, message string) {
+	event := utils.NewAuditEvent(r, eventType)
+	event.UserID = elevation.UserID
+	if user, err := utils.DB.GetUser(elevation.UserID); err == nil {
+		event.Username = user.Username
+	}
+	event.Message = message
+	utils.DB.RecordAudit(event)
+}
+
+// checkSessionAuth rejects elevation requests and reviews made with an access token
+func (e *Elevations) checkSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if auth := utils.GetAuth(r); auth != nil && auth.Method == utils.AuthMethodToken {
+		utils.ResponseErrorStatus(w, errors.New("access tokens cannot request or review elevations"), http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,202 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"strings"
+)
+
+type Groups struct{}
+
+// GetAll lists the groups of the caller's tenant, or all groups for admins
+func (g *Groups) GetAll(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	utils.ResponseSuccess(w, utils.DB.FilterGroups(actor, utils.DB.ListGroups()))
+}
+
+// GetOne returns a single group
+func (g *Groups) GetOne(w http.ResponseWriter, r *http.Request) {
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	group, err := utils.DB.GetGroup(r.PathValue("id"))
+	if err != nil || !utils.DB.CanAccessGroup(actor, group) {
+		utils.ResponseErrorStatus(w, errors.New("group not found"), http.StatusNotFound)
+		return
+	}
+
+	utils.ResponseSuccess(w, utils.DB.ScopeGroupMembers(actor, group))
+}
+
+// Create adds a group whose members inherit its roles and tenant
+func (g *Groups) Create(w http.ResponseWriter, r *http.Request) {
+	var req schema.CreateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	group, err := utils.DB.CreateGroup(&schema.Group{
+		Name:     req.Name,
+		Roles:    req.Roles,
+		TenantID: req.TenantID,
+		Members:  req.Members,
+	})
+	if err != nil {
+		g.responseError(w, err)
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(group.Members...); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	g.recordAudit(r, schema.AuditGroupCreated, g.describe(group))
+	utils.ResponseSuccess(w, group)
+}
+
+// Update changes the name, roles, tenant or members of a group
+func (g *Groups) Update(w http.ResponseWriter, r *http.Request) {
+	var req schema.UpdateGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	g.updateGroup(w, r, func(group *schema.Group) error {
+		if req.Name != nil {
+			group.Name = *req.Name
+		}
+		if req.Roles != nil {
+			group.Roles = req.Roles
+		}
+		if req.TenantID != nil {
+			group.TenantID = req.TenantID
+		}
+		if req.Members != nil {
+			group.Members = req.Members
+		}
+		return nil
+	})
+}
+
+// Delete removes a group, revoking the roles and tenant it granted its members
+func (g *Groups) Delete(w http.ResponseWriter, r *http.Request) {
+	group, err := utils.DB.GetGroup(r.PathValue("id"))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	members, err := utils.DB.DeleteGroup(group.ID)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(members...); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	g.recordAudit(r, schema.AuditGroupDeleted, group.Name)
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// AddMembers adds users to a group
+func (g *Groups) AddMembers(w http.ResponseWriter, r *http.Request) {
+	var req schema.GroupMembersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	g.updateGroup(w, r, func(group *schema.Group) error {
+		group.Members = append(group.Members, req.UserIDs...)
+		return nil
+	})
+}
+
+// RemoveMember removes a user from a group
+func (g *Groups) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	g.updateGroup(w, r, func(group *schema.Group) error {
+		if !group.HasMember(userID) {
+			return errors.New("user is not a member of the group")
+		}
+
+		members := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			if member != userID {
+				members = append(members, member)
+			}
+		}
+		group.Members = members
+		return nil
+	})
+}
+
+func (g *Groups) updateGroup(w http.ResponseWriter, r *http.Request, update func(group *schema.Group) error) {
+	if _, err := utils.DB.GetGroup(r.PathValue("id")); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	group, changed, err := utils.DB.UpdateGroup(r.PathValue("id"), update)
+	if err != nil {
+		g.responseError(w, err)
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(changed...); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	g.recordAudit(r, schema.AuditGroupUpdated, g.describe(group))
+	utils.ResponseSuccess(w, group)
+}
+
+func (g *Groups) responseError(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrSCIMConflict) {
+		utils.ResponseErrorStatus(w, errors.New("group name already exists"), http.StatusConflict)
+		return
+	}
+	utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+}
+
+// describe summarizes the access a group grants for the audit log
+func (g *Groups) describe(group *schema.Group) string {
+	roles := make([]string, 0, len(group.Roles))
+	for _, role := range group.Roles {
+		roles = append(roles, string(role))
+	}
+
+	tenant := "none"
+	if group.TenantID != nil {
+		tenant = *group.TenantID
+	}
+
+	return fmt.Sprintf("%s: roles=[%s] tenant=%s members=%d",
+		group.Name, strings.Join(roles, ", "), tenant, len(group.Members))
+}
+
+func (g *Groups) recordAudit(r *http.Request, eventType schema.AuditEventType, message string) {
+	event := utils.NewAuditEvent(r, eventType)
+	event.Message = message
+	utils.DB.RecordAudit(event)
+}
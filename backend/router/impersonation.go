@@ -0,0 +1,68 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+)
+
+type Impersonation struct{}
+
+// Start lets an admin view the web UI as another user, with that user's permissions and tenant scope
+func (i *Impersonation) Start(w http.ResponseWriter, r *http.Request) {
+	auth := utils.GetAuth(r)
+	if auth == nil || auth.SessionID == "" {
+		utils.ResponseErrorStatus(w, errors.New("impersonation requires a login session"), http.StatusForbidden)
+		return
+	}
+
+	var req schema.StartImpersonationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	impersonation, err := utils.DB.StartImpersonation(auth.SessionID, r.PathValue("id"), &req)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditImpersonationStarted)
+	event.UserID = impersonation.UserID
+	if user, err := utils.DB.GetUser(impersonation.UserID); err == nil {
+		event.Username = user.Username
+	}
+	event.Message = impersonation.Reason
+	utils.DB.RecordAudit(event)
+
+	utils.ResponseSuccess(w, impersonation)
+}
+
+// Stop ends the impersonation and returns the session to the admin
+func (i *Impersonation) Stop(w http.ResponseWriter, r *http.Request) {
+	auth := utils.GetAuth(r)
+	if auth == nil || auth.ImpersonatorID == "" {
+		utils.ResponseErrorStatus(w, errors.New("session is not impersonating a user"), http.StatusBadRequest)
+		return
+	}
+
+	impersonation, err := utils.DB.StopImpersonation(auth.SessionID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditImpersonationEnded)
+	event.ActorID = auth.ImpersonatorID
+	event.ImpersonatorID = ""
+	event.UserID = impersonation.UserID
+	if user, err := utils.DB.GetUser(impersonation.UserID); err == nil {
+		event.Username = user.Username
+	}
+	utils.DB.RecordAudit(event)
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
@@ -0,0 +1,116 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type Invitations struct{}
+
+// Create invites a new user with a preset role and tenant
+func (i *Invitations) Create(w http.ResponseWriter, r *http.Request) {
+	var req schema.InviteUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	// Tenant admins invite users into their own tenant
+	if tenantID, scoped := utils.DB.GetUserScope(actor); scoped && req.TenantID == nil && tenantID != "" {
+		req.TenantID = &tenantID
+	}
+	if err := utils.DB.CheckUserAssignment(actor, &req.Role, req.TenantID); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusForbidden)
+		return
+	}
+
+	invite, err := utils.DB.InviteUser(&req, utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditUserInvited)
+	event.UserID = invite.User.ID
+	event.Message = invite.User.Email
+
+	if utils.Mail.Enabled() && invite.User.Email != "" {
+		if err := utils.Mail.Send(invite.User.Email, "You have been invited to Garage Web UI", i.mailBody(invite)); err != nil {
+			log.Printf("Failed to send invitation email to %s: %v", invite.User.Email, err)
+			event.Message = invite.User.Email + ": invitation email not sent"
+		} else {
+			invite.EmailSent = true
+		}
+	}
+
+	// The token is only handed to the admin when the invitee did not receive it
+	if invite.EmailSent {
+		invite.Token = ""
+		invite.InviteURL = ""
+	}
+
+	utils.DB.RecordAudit(event)
+	utils.ResponseSuccess(w, invite)
+}
+
+// Get returns the details of an invitation so the invitee can review it
+func (i *Invitations) Get(w http.ResponseWriter, r *http.Request) {
+	invitation, err := utils.DB.GetInvitation(r.URL.Query().Get("token"))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	utils.ResponseSuccess(w, invitation)
+}
+
+// Accept activates an invited account and logs the new user in
+func (i *Invitations) Accept(w http.ResponseWriter, r *http.Request) {
+	var req schema.AcceptInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := utils.DB.AcceptInvitation(&req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, utils.ErrInvalidInvitation) {
+			status = http.StatusNotFound
+		}
+		utils.ResponseErrorStatus(w, err, status)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditInviteAccepted)
+	event.UserID = user.ID
+	event.Username = user.Username
+	utils.DB.RecordAudit(event)
+
+	auth := &Auth{}
+	auth.startSession(w, r, user)
+}
+
+func (i *Invitations) mailBody(invite *schema.InviteUserResponse) string {
+	var body strings.Builder
+	body.WriteString("Hello,\n\n")
+	fmt.Fprintf(&body, "You have been invited to Garage Web UI with the %s role.\n\n", invite.User.Role)
+	if invite.InviteURL != "" {
+		fmt.Fprintf(&body, "Open the following link to choose your username and password:\n%s\n\n", invite.InviteURL)
+	}
+	fmt.Fprintf(&body, "Invite token: %s\n\n", invite.Token)
+	fmt.Fprintf(&body, "The invitation expires at %s.\n", invite.ExpiresAt.Format("2006-01-02 15:04:05 MST"))
+	return body.String()
+}
@@ -7,8 +7,6 @@ import (
 	"khairul169/garage-webui/utils"
 	"net/http"
 	"time"
-
-	"github.com/gorilla/mux"
 )
 
 type ObjectLocking struct{}
@@ -54,15 +52,11 @@ type GetObjectLegalHoldResponse struct {
 
 // GetBucketObjectLockConfiguration retrieves object lock configuration for a bucket
 func (ol *ObjectLocking) GetBucketObjectLockConfiguration(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !ol.checkPermission(r, schema.PermissionReadBuckets) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-
 	// Get bucket info from Garage
 	body, err := utils.Garage.Fetch(fmt.Sprintf("/v2/GetBucketInfo?id=%s", bucketID), &utils.FetchOptions{})
 	if err != nil {
@@ -88,15 +82,11 @@ func (ol *ObjectLocking) GetBucketObjectLockConfiguration(w http.ResponseWriter,
 
 // PutBucketObjectLockConfiguration sets object lock configuration for a bucket
 func (ol *ObjectLocking) PutBucketObjectLockConfiguration(w http.ResponseWriter, r *http.Request) {
-	// Check permissions - need special object lock permissions
-	if !ol.checkS3Permission(r, schema.S3ActionPutBucketObjectLockConfiguration) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-
 	var req PutBucketObjectLockConfigurationRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -135,16 +125,12 @@ func (ol *ObjectLocking) PutBucketObjectLockConfiguration(w http.ResponseWriter,
 
 // GetObjectRetention retrieves retention settings for an object
 func (ol *ObjectLocking) GetObjectRetention(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !ol.checkS3Permission(r, schema.S3ActionGetObjectRetention) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	objectKey := r.PathValue("objectKey")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-	objectKey := vars["objectKey"]
-
 	// In a full implementation, this would query Garage for object retention
 	// For now, return a simulated response
 	response := GetObjectRetentionResponse{
@@ -158,16 +144,12 @@ func (ol *ObjectLocking) GetObjectRetention(w http.ResponseWriter, r *http.Reque
 
 // PutObjectRetention sets retention settings for an object
 func (ol *ObjectLocking) PutObjectRetention(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !ol.checkS3Permission(r, schema.S3ActionPutObjectRetention) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	objectKey := r.PathValue("objectKey")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-	objectKey := vars["objectKey"]
-
 	var req PutObjectRetentionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -204,16 +186,12 @@ func (ol *ObjectLocking) PutObjectRetention(w http.ResponseWriter, r *http.Reque
 
 // GetObjectLegalHold retrieves legal hold status for an object
 func (ol *ObjectLocking) GetObjectLegalHold(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !ol.checkS3Permission(r, schema.S3ActionGetObjectLegalHold) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	objectKey := r.PathValue("objectKey")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-	objectKey := vars["objectKey"]
-
 	// In a full implementation, this would query Garage for legal hold status
 	// For now, return a simulated response
 	response := GetObjectLegalHoldResponse{
@@ -229,16 +207,12 @@ func (ol *ObjectLocking) GetObjectLegalHold(w http.ResponseWriter, r *http.Reque
 
 // PutObjectLegalHold sets legal hold status for an object
 func (ol *ObjectLocking) PutObjectLegalHold(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !ol.checkS3Permission(r, schema.S3ActionPutObjectLegalHold) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	objectKey := r.PathValue("objectKey")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-	objectKey := vars["objectKey"]
-
 	var req PutObjectLegalHoldRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -269,15 +243,11 @@ func (ol *ObjectLocking) PutObjectLegalHold(w http.ResponseWriter, r *http.Reque
 
 // ListObjectsWithLocking lists objects with their locking information
 func (ol *ObjectLocking) ListObjectsWithLocking(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !ol.checkS3Permission(r, schema.S3ActionListBucket) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	if !checkResourceScope(w, r, bucketID, "") {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-
 	// Get query parameters
 	prefix := r.URL.Query().Get("prefix")
 	delimiter := r.URL.Query().Get("delimiter")
@@ -310,44 +280,3 @@ func (ol *ObjectLocking) ListObjectsWithLocking(w http.ResponseWriter, r *http.R
 
 	utils.ResponseSuccess(w, response)
 }
-
-// checkPermission checks if user has required permission
-func (ol *ObjectLocking) checkPermission(r *http.Request, permission schema.Permission) bool {
-	userID := utils.Session.Get(r, "user_id")
-	if userID == nil {
-		return false
-	}
-
-	user, err := utils.DB.GetUser(userID.(string))
-	if err != nil {
-		return false
-	}
-
-	return user.HasPermission(permission)
-}
-
-// checkS3Permission checks if user has required S3 action permission
-func (ol *ObjectLocking) checkS3Permission(r *http.Request, action schema.S3Action) bool {
-	userID := utils.Session.Get(r, "user_id")
-	if userID == nil {
-		return false
-	}
-
-	user, err := utils.DB.GetUser(userID.(string))
-	if err != nil {
-		return false
-	}
-
-	// For now, map S3 actions to basic permissions
-	// In a full implementation, you'd check the user's S3 policies
-	switch action {
-	case schema.S3ActionGetObjectRetention, schema.S3ActionGetObjectLegalHold, schema.S3ActionGetBucketObjectLockConfiguration:
-		return user.HasPermission(schema.PermissionReadBuckets)
-	case schema.S3ActionPutObjectRetention, schema.S3ActionPutObjectLegalHold, schema.S3ActionPutBucketObjectLockConfiguration:
-		return user.HasPermission(schema.PermissionWriteBuckets) || user.Role == schema.RoleAdmin
-	case schema.S3ActionListBucket:
-		return user.HasPermission(schema.PermissionReadBuckets)
-	default:
-		return user.HasPermission(schema.PermissionWriteBuckets)
-	}
-}
\ No newline at end of file
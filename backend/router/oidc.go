@@ -0,0 +1,108 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+type OIDC struct{}
+
+// Login redirects the browser to the identity provider
+func (o *OIDC) Login(w http.ResponseWriter, r *http.Request) {
+	if !utils.OIDC.Enabled() {
+		utils.ResponseErrorStatus(w, errors.New("OIDC login is not configured"), http.StatusNotFound)
+		return
+	}
+
+	login, err := utils.OIDC.BeginLogin(r.Context(), o.getRedirectURL(r))
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.Session.Set(r, "oidc_state", login.State)
+	utils.Session.Set(r, "oidc_nonce", login.Nonce)
+	utils.Session.Set(r, "oidc_verifier", login.Verifier)
+
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// Callback completes the authorization code flow and logs the user in
+func (o *OIDC) Callback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	state, _ := utils.Session.Get(r, "oidc_state").(string)
+	nonce, _ := utils.Session.Get(r, "oidc_nonce").(string)
+	verifier, _ := utils.Session.Get(r, "oidc_verifier").(string)
+
+	// The state is single-use
+	utils.Session.Set(r, "oidc_state", "")
+
+	if errCode := query.Get("error"); errCode != "" {
+		o.redirectError(w, r, fmt.Errorf("%s: %s", errCode, query.Get("error_description")))
+		return
+	}
+
+	if state == "" || query.Get("state") != state {
+		o.redirectError(w, r, errors.New("invalid state"))
+		return
+	}
+
+	identity, err := utils.OIDC.Exchange(r.Context(), o.getRedirectURL(r), query.Get("code"), verifier, nonce)
+	if err != nil {
+		o.redirectError(w, r, err)
+		return
+	}
+
+	role, tenantID, err := utils.OIDC.GetMapping().Resolve(identity.Groups, identity.Email)
+	if err != nil {
+		o.redirectError(w, r, err)
+		return
+	}
+
+	user, err := utils.DB.UpsertExternalUser(schema.AuthSourceOIDC, identity.Subject, identity.Username, identity.Email, role, tenantID)
+	if err != nil {
+		o.redirectError(w, r, err)
+		return
+	}
+
+	// Locked out accounts and clients cannot log in through the provider either
+	if _, allowed := utils.LoginGuard.Check(utils.GetClientIP(r), user.Username); !allowed {
+		event := utils.NewAuditEvent(r, schema.AuditLoginBlocked)
+		event.UserID = user.ID
+		event.Username = user.Username
+		utils.DB.RecordAudit(event)
+
+		o.redirectError(w, r, utils.ErrLoginLocked)
+		return
+	}
+
+	if _, err := createLoginSession(w, r, user); err != nil {
+		o.redirectError(w, r, err)
+		return
+	}
+
+	http.Redirect(w, r, os.Getenv("BASE_PATH")+"/", http.StatusFound)
+}
+
+// getRedirectURL derives the callback URL from the request when OIDC_REDIRECT_URL is not set
+func (o *OIDC) getRedirectURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/api/auth/oidc/callback", scheme, r.Host, os.Getenv("BASE_PATH"))
+}
+
+// redirectError sends the browser back to the login page with an error message
+func (o *OIDC) redirectError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("OIDC login failed: %v", err)
+	target := os.Getenv("BASE_PATH") + "/auth/login?error=" + url.QueryEscape(err.Error())
+	http.Redirect(w, r, target, http.StatusFound)
+}
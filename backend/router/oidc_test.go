@@ -0,0 +1,252 @@
+package router
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"khairul169/garage-webui/utils"
+	"math/big"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockIssuer is a minimal OpenID Connect provider issuing RS256 ID tokens
+type mockIssuer struct {
+	*httptest.Server
+	key   *rsa.PrivateKey
+	mutex sync.Mutex
+	// codes maps authorization codes to the PKCE challenge and nonce they were issued for
+	codes map[string]mockAuthorization
+}
+
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuer := &mockIssuer{key: key, codes: make(map[string]mockAuthorization)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.URL,
+			"authorization_endpoint":                issuer.URL + "/authorize",
+			"token_endpoint":                        issuer.URL + "/token",
+			"jwks_uri":                              issuer.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"alg": "RS256",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("POST /token", issuer.token)
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// authorize registers a code as if the user had logged in at the provider
+func (m *mockIssuer) authorize(code, challenge, nonce string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.codes[code] = mockAuthorization{challenge: challenge, nonce: nonce}
+}
+
+func (m *mockIssuer) token(w http.ResponseWriter, r *http.Request) {
+	m.mutex.Lock()
+	authorization, ok := m.codes[r.FormValue("code")]
+	delete(m.codes, r.FormValue("code"))
+	m.mutex.Unlock()
+
+	verifier := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(verifier[:]) != authorization.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	idToken := m.sign(map[string]interface{}{
+		"iss":                m.URL,
+		"sub":                "alice-subject",
+		"aud":                "webui",
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+		"nonce":              authorization.nonce,
+		"preferred_username": "alice",
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (m *mockIssuer) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCCallback(t *testing.T) {
+	issuer := newMockIssuer(t)
+	t.Setenv("DATA_DIR", t.TempDir())
+	t.Setenv("OIDC_ISSUER_URL", issuer.URL)
+	t.Setenv("OIDC_CLIENT_ID", "webui")
+	t.Setenv("OIDC_CLIENT_SECRET", "secret")
+	t.Setenv("OIDC_DEFAULT_ROLE", "user")
+
+	sessionMgr, err := utils.InitSessionManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oidc := &OIDC{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /auth/oidc/login", oidc.Login)
+	mux.HandleFunc("GET /auth/oidc/callback", oidc.Callback)
+	webui := httptest.NewServer(sessionMgr.LoadAndSave(mux))
+	t.Cleanup(webui.Close)
+
+	// login starts a flow with a fresh cookie session and returns the client and authorization request
+	login := func(t *testing.T) (*http.Client, url.Values) {
+		jar, _ := cookiejar.New(nil)
+		client := &http.Client{
+			Jar: jar,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+		res, err := client.Get(webui.URL + "/auth/oidc/login")
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		if err != nil || !strings.HasPrefix(location.String(), issuer.URL+"/authorize") {
+			t.Fatalf("login redirected to %q", res.Header.Get("Location"))
+		}
+		query := location.Query()
+		if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+			t.Fatalf("authorization request without PKCE: %s", location)
+		}
+		return client, query
+	}
+
+	callback := func(t *testing.T, client *http.Client, code, state string) string {
+		res, err := client.Get(webui.URL + "/auth/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode())
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.Header.Get("Location")
+	}
+
+	t.Run("success", func(t *testing.T) {
+		client, query := login(t)
+		issuer.authorize("code-success", query.Get("code_challenge"), query.Get("nonce"))
+
+		if location := callback(t, client, "code-success", query.Get("state")); location != "/" {
+			t.Fatalf("callback redirected to %q, want /", location)
+		}
+		if _, err := utils.DB.GetUserByUsername("alice"); err != nil {
+			t.Errorf("user was not provisioned: %v", err)
+		}
+	})
+
+	t.Run("invalid state", func(t *testing.T) {
+		client, query := login(t)
+		issuer.authorize("code-state", query.Get("code_challenge"), query.Get("nonce"))
+
+		location := callback(t, client, "code-state", "forged-state")
+		if !strings.Contains(location, "error=invalid+state") {
+			t.Errorf("callback redirected to %q, want an invalid state error", location)
+		}
+	})
+
+	t.Run("state is single use", func(t *testing.T) {
+		client, query := login(t)
+		issuer.authorize("code-replay", query.Get("code_challenge"), query.Get("nonce"))
+		callback(t, client, "code-replay", "forged-state")
+
+		issuer.authorize("code-replay", query.Get("code_challenge"), query.Get("nonce"))
+		location := callback(t, client, "code-replay", query.Get("state"))
+		if !strings.Contains(location, "error=invalid+state") {
+			t.Errorf("callback redirected to %q, want an invalid state error", location)
+		}
+	})
+
+	t.Run("invalid nonce", func(t *testing.T) {
+		client, query := login(t)
+		issuer.authorize("code-nonce", query.Get("code_challenge"), "other-nonce")
+
+		location := callback(t, client, "code-nonce", query.Get("state"))
+		if !strings.Contains(location, "nonce") {
+			t.Errorf("callback redirected to %q, want a nonce error", location)
+		}
+	})
+
+	t.Run("invalid PKCE verifier", func(t *testing.T) {
+		client, query := login(t)
+		issuer.authorize("code-pkce", "challenge-of-another-login", query.Get("nonce"))
+
+		location := callback(t, client, "code-pkce", query.Get("state"))
+		if !strings.Contains(location, "invalid_grant") {
+			t.Errorf("callback redirected to %q, want a failed code exchange", location)
+		}
+	})
+
+	t.Run("locked account", func(t *testing.T) {
+		user, err := utils.DB.GetUserByUsername("alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lockedUntil := time.Now().Add(time.Hour)
+		utils.DB.Users[user.ID].LockedUntil = &lockedUntil
+		defer func() { utils.DB.Users[user.ID].LockedUntil = nil }()
+
+		client, query := login(t)
+		issuer.authorize("code-locked", query.Get("code_challenge"), query.Get("nonce"))
+
+		location := callback(t, client, "code-locked", query.Get("state"))
+		if !strings.Contains(location, url.QueryEscape(utils.ErrLoginLocked.Error())) {
+			t.Errorf("callback redirected to %q, want a lockout error", location)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type PasswordReset struct{}
+
+// Forgot issues a reset token and mails it to the user. The response never
+// reveals whether the account exists.
+func (p *PasswordReset) Forgot(w http.ResponseWriter, r *http.Request) {
+	var body schema.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	body.Username = strings.TrimSpace(body.Username)
+	if body.Username == "" {
+		utils.ResponseErrorStatus(w, errors.New("username is required"), http.StatusBadRequest)
+		return
+	}
+
+	// Locked out clients cannot request resets, and requests are limited per IP and
+	// username so the endpoint cannot be used to spam users or invalidate their tokens
+	ip := utils.GetClientIP(r)
+	if _, allowed := utils.LoginGuard.Check(ip, body.Username); !allowed {
+		utils.ResponseErrorStatus(w, utils.ErrLoginLocked, http.StatusTooManyRequests)
+		return
+	}
+	if !utils.LoginGuard.RecordPasswordReset(ip, body.Username) {
+		utils.ResponseErrorStatus(w, utils.ErrResetThrottled, http.StatusTooManyRequests)
+		return
+	}
+
+	response := map[string]string{
+		"message": "If the account exists, password reset instructions have been sent",
+	}
+
+	user, err := utils.DB.FindUserForReset(body.Username)
+	if err != nil || !user.Enabled {
+		utils.ResponseSuccess(w, response)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditPasswordResetRequested)
+	event.UserID = user.ID
+	event.Username = user.Username
+
+	// Without a way to deliver the token, an admin has to create one with
+	// POST /users/{id}/password-reset; any token they handed out stays valid
+	if !utils.Mail.Enabled() || user.Email == "" {
+		event.Message = "not sent: no email delivery for this user"
+		utils.DB.RecordAudit(event)
+		utils.ResponseSuccess(w, response)
+		return
+	}
+
+	reset, err := utils.DB.CreatePasswordReset(user.ID)
+	if err != nil {
+		utils.ResponseSuccess(w, response)
+		return
+	}
+
+	if err := utils.Mail.Send(user.Email, "Reset your Garage Web UI password", p.mailBody(user, reset)); err != nil {
+		log.Printf("Failed to send password reset email to %s: %v", user.Username, err)
+		event.Message = err.Error()
+	}
+
+	utils.DB.RecordAudit(event)
+	utils.ResponseSuccess(w, response)
+}
+
+// Reset consumes a reset token and sets the new password
+func (p *PasswordReset) Reset(w http.ResponseWriter, r *http.Request) {
+	var body schema.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	user, err := utils.DB.ConsumePasswordReset(body.Token, body.NewPassword)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditPasswordReset)
+	event.UserID = user.ID
+	event.Username = user.Username
+	utils.DB.RecordAudit(event)
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// Create lets an admin generate a reset token to hand out to a user
+func (p *PasswordReset) Create(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := getScopedUser(w, r, userID); !ok {
+		return
+	}
+
+	reset, err := utils.DB.CreatePasswordReset(userID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditPasswordResetRequested)
+	event.UserID = userID
+	utils.DB.RecordAudit(event)
+
+	utils.ResponseSuccess(w, reset)
+}
+
+func (p *PasswordReset) mailBody(user *schema.User, reset *schema.PasswordResetResponse) string {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s,\n\n", user.Username)
+	body.WriteString("A password reset was requested for your Garage Web UI account.\n\n")
+	if reset.ResetURL != "" {
+		fmt.Fprintf(&body, "Open the following link to choose a new password:\n%s\n\n", reset.ResetURL)
+	}
+	fmt.Fprintf(&body, "Reset token: %s\n\n", reset.Token)
+	fmt.Fprintf(&body, "The token expires at %s. If you did not request a reset, you can ignore this email.\n",
+		reset.ExpiresAt.Format("2006-01-02 15:04:05 MST"))
+	return body.String()
+}
@@ -1,15 +1,138 @@
 package router
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"strconv"
 	"strings"
 )
 
+// garageEndpointPermissions maps each proxied Garage admin API endpoint to the
+// permission required to call it. Endpoints not listed here are never proxied.
+var garageEndpointPermissions = map[string]schema.Permission{
+	// Cluster
+	"GetClusterHealth":            schema.PermissionReadCluster,
+	"GetClusterStatus":            schema.PermissionReadCluster,
+	"GetClusterStatistics":        schema.PermissionReadCluster,
+	"ConnectClusterNodes":         schema.PermissionWriteCluster,
+	"GetClusterLayout":            schema.PermissionReadCluster,
+	"GetClusterLayoutHistory":     schema.PermissionReadCluster,
+	"PreviewClusterLayoutChanges": schema.PermissionReadCluster,
+	"UpdateClusterLayout":         schema.PermissionWriteCluster,
+	"ApplyClusterLayout":          schema.PermissionWriteCluster,
+	"RevertClusterLayout":         schema.PermissionWriteCluster,
+	"ClusterLayoutSkipDeadNodes":  schema.PermissionWriteCluster,
+
+	// Nodes, workers and blocks
+	"GetNodeInfo":            schema.PermissionReadCluster,
+	"GetNodeStatistics":      schema.PermissionReadCluster,
+	"CreateMetadataSnapshot": schema.PermissionWriteCluster,
+	"LaunchRepairOperation":  schema.PermissionWriteCluster,
+	"ListWorkers":            schema.PermissionReadCluster,
+	"GetWorkerInfo":          schema.PermissionReadCluster,
+	"GetWorkerVariable":      schema.PermissionReadCluster,
+	"SetWorkerVariable":      schema.PermissionWriteCluster,
+	"ListBlockErrors":        schema.PermissionReadCluster,
+	"GetBlockInfo":           schema.PermissionReadCluster,
+	"RetryBlockResync":       schema.PermissionWriteCluster,
+	"PurgeBlocks":            schema.PermissionWriteCluster,
+
+	// Admin API tokens grant access to Garage itself
+	"ListAdminTokens":          schema.PermissionSystemAdmin,
+	"GetAdminTokenInfo":        schema.PermissionSystemAdmin,
+	"GetCurrentAdminTokenInfo": schema.PermissionSystemAdmin,
+	"CreateAdminToken":         schema.PermissionSystemAdmin,
+	"UpdateAdminToken":         schema.PermissionSystemAdmin,
+	"DeleteAdminToken":         schema.PermissionSystemAdmin,
+
+	// Access keys
+	"ListKeys":   schema.PermissionReadKeys,
+	"GetKeyInfo": schema.PermissionReadKeys,
+	"CreateKey":  schema.PermissionWriteKeys,
+	"ImportKey":  schema.PermissionWriteKeys,
+	"UpdateKey":  schema.PermissionWriteKeys,
+	"DeleteKey":  schema.PermissionDeleteKeys,
+
+	// Buckets
+	"ListBuckets":              schema.PermissionReadBuckets,
+	"GetBucketInfo":            schema.PermissionReadBuckets,
+	"InspectObject":            schema.PermissionReadBuckets,
+	"CreateBucket":             schema.PermissionWriteBuckets,
+	"UpdateBucket":             schema.PermissionWriteBuckets,
+	"CleanupIncompleteUploads": schema.PermissionWriteBuckets,
+	"AddBucketAlias":           schema.PermissionWriteBuckets,
+	"RemoveBucketAlias":        schema.PermissionWriteBuckets,
+	"AllowBucketKey":           schema.PermissionWriteBuckets,
+	"DenyBucketKey":            schema.PermissionWriteBuckets,
+	"DeleteBucket":             schema.PermissionDeleteBuckets,
+}
+
+// garageBucketQueryParams and garageKeyQueryParams name the query parameter
+// holding the bucket or access key an endpoint operates on
+var garageBucketQueryParams = map[string]string{
+	"GetBucketInfo": "id",
+	"UpdateBucket":  "id",
+	"DeleteBucket":  "id",
+	"InspectObject": "bucketId",
+}
+
+var garageKeyQueryParams = map[string]string{
+	"GetKeyInfo": "id",
+	"UpdateKey":  "id",
+	"DeleteKey":  "id",
+}
+
+// garageRequestRefs holds the buckets and keys referenced by a request body
+type garageRequestRefs struct {
+	BucketID    string          `json:"bucketId"`
+	AccessKeyID string          `json:"accessKeyId"`
+	LocalAlias  json.RawMessage `json:"localAlias"`
+}
+
 func ProxyHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	endpoint := strings.TrimPrefix(r.URL.Path, "/v2/")
+	if err := checkProxyScope(r, user, endpoint); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusForbidden)
+		return
+	}
+
+	// Destructive operations may have to wait for a second admin
+	if utils.DB.RequiresApproval(endpoint) {
+		var body []byte
+		if r.Body != nil {
+			if body, err = io.ReadAll(r.Body); err != nil {
+				utils.ResponseError(w, err)
+				return
+			}
+		}
+
+		permission, _ := getGarageEndpointPermission(r)
+		queueApproval(w, r, &schema.ApprovalRequest{
+			Operation:  endpoint,
+			Permission: permission,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+			Body:       string(body),
+			Summary:    strings.TrimSpace(fmt.Sprintf("%s %s", endpoint, r.URL.RawQuery)),
+		})
+		return
+	}
+
 	target, err := url.Parse(utils.Garage.GetAdminEndpoint())
 	if err != nil {
 		utils.ResponseError(w, err)
@@ -21,8 +144,247 @@ func ProxyHandler(w http.ResponseWriter, r *http.Request) {
 			r.SetURL(target)
 			r.Out.URL.Path = strings.TrimPrefix(r.In.URL.Path, "/api")
 			r.Out.Header.Set("Authorization", fmt.Sprintf("Bearer %s", utils.Garage.GetAdminKey()))
+
+			// Responses are inspected uncompressed
+			r.Out.Header.Del("Accept-Encoding")
+		},
+		ModifyResponse: func(res *http.Response) error {
+			return scopeProxyResponse(res, user, endpoint)
 		},
 	}
 
 	proxy.ServeHTTP(w, r)
 }
+
+// checkProxyScope rejects requests for buckets and keys outside the user's tenant
+func checkProxyScope(r *http.Request, user *schema.User, endpoint string) error {
+	if _, scoped := utils.DB.GetUserScope(user); !scoped {
+		return nil
+	}
+
+	query := r.URL.Query()
+	if param, ok := garageBucketQueryParams[endpoint]; ok {
+		if query.Get(param) == "" {
+			return errors.New("bucket must be referenced by id")
+		}
+		if !utils.DB.CanAccessBucket(user, query.Get(param)) {
+			return utils.ErrOutsideTenant
+		}
+	}
+	if param, ok := garageKeyQueryParams[endpoint]; ok {
+		if query.Get(param) == "" {
+			return errors.New("access key must be referenced by id")
+		}
+		if !utils.DB.CanAccessKey(user, query.Get(param)) {
+			return utils.ErrOutsideTenant
+		}
+	}
+
+	if r.Body == nil {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+
+	// Garage only reads the exact field names, while json.Unmarshal also accepts other
+	// cases and keeps the last duplicate, so anything else could smuggle in another ID
+	if err := checkExactKeys(body, "bucketId", "accessKeyId", "localAlias"); err != nil {
+		return err
+	}
+
+	var refs garageRequestRefs
+	if err := json.Unmarshal(body, &refs); err != nil {
+		return errors.New("invalid request body")
+	}
+
+	if endpoint == "CreateBucket" && len(refs.LocalAlias) > 0 {
+		if err := checkExactKeys(refs.LocalAlias, "accessKeyId"); err != nil {
+			return err
+		}
+
+		var localAlias struct {
+			AccessKeyID string `json:"accessKeyId"`
+		}
+		if err := json.Unmarshal(refs.LocalAlias, &localAlias); err == nil {
+			refs.AccessKeyID = localAlias.AccessKeyID
+		}
+	}
+
+	if refs.BucketID != "" && !utils.DB.CanAccessBucket(user, refs.BucketID) {
+		return utils.ErrOutsideTenant
+	}
+	if refs.AccessKeyID != "" && !utils.DB.CanAccessKey(user, refs.AccessKeyID) {
+		return utils.ErrOutsideTenant
+	}
+	return nil
+}
+
+// checkResourceScope rejects requests for a bucket or access key outside the user's tenant
+func checkResourceScope(w http.ResponseWriter, r *http.Request, bucketID, accessKeyID string) bool {
+	user, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return false
+	}
+
+	if bucketID != "" && !utils.DB.CanAccessBucket(user, bucketID) {
+		utils.ResponseErrorStatus(w, utils.ErrOutsideTenant, http.StatusForbidden)
+		return false
+	}
+	if accessKeyID != "" && !utils.DB.CanAccessKey(user, accessKeyID) {
+		utils.ResponseErrorStatus(w, utils.ErrOutsideTenant, http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// checkExactKeys rejects a JSON object whose keys match one of the given names only
+// case-insensitively, or that repeats one of them
+func checkExactKeys(data []byte, names ...string) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return errors.New("invalid request body")
+		}
+		key, _ := tok.(string)
+
+		for _, name := range names {
+			if !strings.EqualFold(key, name) {
+				continue
+			}
+			if key != name {
+				return fmt.Errorf("invalid request body: unexpected field %q", key)
+			}
+			if seen[name] {
+				return fmt.Errorf("invalid request body: duplicate field %q", key)
+			}
+			seen[name] = true
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return errors.New("invalid request body")
+		}
+	}
+	return nil
+}
+
+// scopeProxyResponse filters bucket and key listings to the user's tenant and
+// keeps track of the tenant owning buckets and keys as they are created or deleted
+func scopeProxyResponse(res *http.Response, user *schema.User, endpoint string) error {
+	if res.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	switch endpoint {
+	case "ListBuckets":
+		return rewriteProxyResponse(res, func(body []byte) ([]byte, error) {
+			return filterGarageList(body, func(id string) bool {
+				return utils.DB.CanAccessBucket(user, id)
+			})
+		})
+
+	case "ListKeys":
+		return rewriteProxyResponse(res, func(body []byte) ([]byte, error) {
+			return filterGarageList(body, func(id string) bool {
+				return utils.DB.CanAccessKey(user, id)
+			})
+		})
+
+	case "CreateBucket", "CreateKey", "ImportKey":
+		tenantID, _ := utils.DB.GetUserScope(user)
+		if tenantID == "" {
+			return nil
+		}
+		return rewriteProxyResponse(res, func(body []byte) ([]byte, error) {
+			var created struct {
+				ID          string `json:"id"`
+				AccessKeyID string `json:"accessKeyId"`
+			}
+			if err := json.Unmarshal(body, &created); err != nil {
+				return nil, err
+			}
+
+			if endpoint == "CreateBucket" {
+				return body, utils.DB.SetBucketTenant(created.ID, tenantID, user.ID)
+			}
+			return body, utils.DB.SetKeyTenant(created.AccessKeyID, tenantID, user.ID)
+		})
+
+	case "DeleteBucket":
+		bucketID := res.Request.URL.Query().Get("id")
+		if err := utils.DB.DeleteBucketGrants(bucketID); err != nil {
+			return err
+		}
+		return utils.DB.SetBucketTenant(bucketID, "", "")
+
+	case "DeleteKey":
+		return utils.DB.SetKeyTenant(res.Request.URL.Query().Get("id"), "", "")
+	}
+
+	return nil
+}
+
+func rewriteProxyResponse(res *http.Response, rewrite func(body []byte) ([]byte, error)) error {
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	body, err = rewrite(body)
+	if err != nil {
+		return err
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	res.ContentLength = int64(len(body))
+	res.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// filterGarageList keeps the items of a Garage list response whose id is allowed
+func filterGarageList(body []byte, allow func(id string) bool) ([]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+
+	result := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		var ref struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(item, &ref); err != nil {
+			return nil, err
+		}
+		if allow(ref.ID) {
+			result = append(result, item)
+		}
+	}
+
+	return json.Marshal(result)
+}
+
+// getGarageEndpointPermission looks up the permission for a "/v2/{Endpoint}" request
+func getGarageEndpointPermission(r *http.Request) (schema.Permission, bool) {
+	endpoint, ok := strings.CutPrefix(r.URL.Path, "/v2/")
+	if !ok || strings.Contains(endpoint, "/") {
+		return "", false
+	}
+
+	permission, ok := garageEndpointPermissions[endpoint]
+	return permission, ok
+}
@@ -0,0 +1,25 @@
+package router
+
+import "testing"
+
+func TestCheckExactKeys(t *testing.T) {
+	tests := []struct {
+		body    string
+		wantErr bool
+	}{
+		{`{"bucketId":"a","accessKeyId":"b","permissions":{"read":true}}`, false},
+		{`{"bucketId":"a","BucketId":"b"}`, true},
+		{`{"BUCKETID":"a"}`, true},
+		{`{"bucketId":"a","bucketId":"b"}`, true},
+		{`{"accessKeyId":"a","accesskeyid":"b"}`, true},
+		{`{"localAlias":{"accessKeyId":"a"}}`, false},
+		{`[]`, false},
+	}
+
+	for _, tt := range tests {
+		err := checkExactKeys([]byte(tt.body), "bucketId", "accessKeyId", "localAlias")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkExactKeys(%s) error = %v, want error %v", tt.body, err, tt.wantErr)
+		}
+	}
+}
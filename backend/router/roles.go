@@ -0,0 +1,98 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"strings"
+)
+
+type Roles struct{}
+
+// GetAll lists the built-in and custom roles
+func (ro *Roles) GetAll(w http.ResponseWriter, r *http.Request) {
+	utils.ResponseSuccess(w, utils.DB.ListRoles())
+}
+
+// GetOne returns a built-in or custom role
+func (ro *Roles) GetOne(w http.ResponseWriter, r *http.Request) {
+	role, err := utils.DB.GetRole(schema.Role(r.PathValue("name")))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	utils.ResponseSuccess(w, role)
+}
+
+// Create defines a custom role
+func (ro *Roles) Create(w http.ResponseWriter, r *http.Request) {
+	var req schema.CreateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	role, err := utils.DB.CreateRole(&req)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ro.recordAudit(r, schema.AuditRoleCreated, role)
+	utils.ResponseSuccess(w, role)
+}
+
+// Update changes the description or permissions of a custom role
+func (ro *Roles) Update(w http.ResponseWriter, r *http.Request) {
+	var req schema.UpdateRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	role, err := utils.DB.UpdateRole(schema.Role(r.PathValue("name")), &req)
+	if errors.Is(err, utils.ErrRoleNotFound) {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	ro.recordAudit(r, schema.AuditRoleUpdated, role)
+	utils.ResponseSuccess(w, role)
+}
+
+// Delete removes a custom role that is not assigned to anyone
+func (ro *Roles) Delete(w http.ResponseWriter, r *http.Request) {
+	name := schema.Role(r.PathValue("name"))
+	err := utils.DB.DeleteRole(name)
+	if errors.Is(err, utils.ErrRoleNotFound) {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusConflict)
+		return
+	}
+
+	ro.recordAudit(r, schema.AuditRoleDeleted, &schema.RoleDefinition{Name: name})
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+func (ro *Roles) recordAudit(r *http.Request, eventType schema.AuditEventType, role *schema.RoleDefinition) {
+	event := utils.NewAuditEvent(r, eventType)
+	event.Message = string(role.Name)
+	if len(role.Permissions) > 0 {
+		permissions := make([]string, 0, len(role.Permissions))
+		for _, permission := range role.Permissions {
+			permissions = append(permissions, string(permission))
+		}
+		event.Message += ": " + strings.Join(permissions, ", ")
+	}
+	utils.DB.RecordAudit(event)
+}
@@ -1,76 +1,289 @@
 package router
 
 import (
+	"fmt"
 	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
 	"net/http"
+	"slices"
 )
 
-func HandleApiRouter() *http.ServeMux {
-	mux := http.NewServeMux()
+// apiRoute is a registered API route and the access it requires
+type apiRoute struct {
+	Pattern     string
+	Requirement middleware.Requirement
+}
+
+// routeMux is a ServeMux that records the patterns registered on it, so routes
+// added without a declared requirement can be detected
+type routeMux struct {
+	*http.ServeMux
+	patterns []string
+}
+
+func newRouteMux() *routeMux {
+	return &routeMux{ServeMux: http.NewServeMux()}
+}
+
+func (m *routeMux) Handle(pattern string, handler http.Handler) {
+	m.patterns = append(m.patterns, pattern)
+	m.ServeMux.Handle(pattern, handler)
+}
+
+func (m *routeMux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.Handle(pattern, http.HandlerFunc(handler))
+}
+
+// apiRouter registers every API route together with its access requirement. Public
+// routes are served as is, the others behind authentication, CSRF protection and
+// the authorization check of their requirement.
+type apiRouter struct {
+	public  *routeMux
+	private *routeMux
+	routes  []apiRoute
+	// protected serves the private routes once the request is authenticated
+	protected http.Handler
+}
+
+func (a *apiRouter) handle(pattern string, req middleware.Requirement, handler http.HandlerFunc) {
+	a.routes = append(a.routes, apiRoute{Pattern: pattern, Requirement: req})
+
+	if req.IsPublic() {
+		a.public.HandleFunc(pattern, handler)
+		return
+	}
+	a.private.HandleFunc(pattern, middleware.Authorize(req, handler))
+}
+
+func (a *apiRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := a.public.Handler(r); pattern != "" {
+		a.public.ServeHTTP(w, r)
+		return
+	}
+	a.protected.ServeHTTP(w, r)
+}
+
+// checkRoutes verifies that every pattern served by the router was registered
+// with handle and therefore declares the access it requires
+func (a *apiRouter) checkRoutes() error {
+	declared := make(map[string]int)
+	for _, route := range a.routes {
+		if !route.Requirement.IsSet() {
+			return fmt.Errorf("route %q is registered without an access requirement", route.Pattern)
+		}
+		declared[route.Pattern]++
+	}
+
+	for _, pattern := range append(slices.Clone(a.public.patterns), a.private.patterns...) {
+		if declared[pattern] == 0 {
+			return fmt.Errorf("route %q is registered without an access requirement", pattern)
+		}
+		declared[pattern]--
+	}
+	return nil
+}
+
+func HandleApiRouter() http.Handler {
+	router := newApiRouter()
+	if err := router.checkRoutes(); err != nil {
+		panic(err)
+	}
+	return router
+}
+
+func newApiRouter() *apiRouter {
+	public := middleware.Public()
+	authenticated := middleware.Authenticated()
+	require := middleware.RequirePermission
+
+	router := &apiRouter{
+		public:  newRouteMux(),
+		private: newRouteMux(),
+	}
+	router.protected = middleware.AuthMiddleware(middleware.CSRFMiddleware(router.private))
 
 	auth := &Auth{}
-	mux.HandleFunc("POST /auth/login", auth.Login)
+	router.handle("POST /auth/login", public, auth.Login)
+	router.handle("POST /auth/login/2fa", public, auth.LoginTwoFactor)
+	router.handle("GET /auth/providers", public, auth.GetProviders)
+
+	passwordReset := &PasswordReset{}
+	router.handle("POST /auth/forgot", public, passwordReset.Forgot)
+	router.handle("POST /auth/reset", public, passwordReset.Reset)
+
+	invitations := &Invitations{}
+	router.handle("GET /auth/invite", public, invitations.Get)
+	router.handle("POST /auth/invite/accept", public, invitations.Accept)
+
+	webAuthn := &WebAuthn{}
+	router.handle("POST /auth/webauthn/login/begin", public, webAuthn.BeginLogin)
+	router.handle("POST /auth/webauthn/login/finish", public, webAuthn.FinishLogin)
+
+	// SCIM provisioning, authenticated with the SCIM_TOKEN instead of a user session
+	scim := &SCIM{}
+	router.handle("GET /scim/v2/ServiceProviderConfig", public, scim.Authenticate(scim.GetServiceProviderConfig))
+	router.handle("GET /scim/v2/Users", public, scim.Authenticate(scim.GetUsers))
+	router.handle("POST /scim/v2/Users", public, scim.Authenticate(scim.CreateUser))
+	router.handle("GET /scim/v2/Users/{id}", public, scim.Authenticate(scim.GetUser))
+	router.handle("PUT /scim/v2/Users/{id}", public, scim.Authenticate(scim.ReplaceUser))
+	router.handle("PATCH /scim/v2/Users/{id}", public, scim.Authenticate(scim.PatchUser))
+	router.handle("DELETE /scim/v2/Users/{id}", public, scim.Authenticate(scim.DeleteUser))
+	router.handle("GET /scim/v2/Groups", public, scim.Authenticate(scim.GetGroups))
+	router.handle("POST /scim/v2/Groups", public, scim.Authenticate(scim.CreateGroup))
+	router.handle("GET /scim/v2/Groups/{id}", public, scim.Authenticate(scim.GetGroup))
+	router.handle("PUT /scim/v2/Groups/{id}", public, scim.Authenticate(scim.ReplaceGroup))
+	router.handle("PATCH /scim/v2/Groups/{id}", public, scim.Authenticate(scim.PatchGroup))
+	router.handle("DELETE /scim/v2/Groups/{id}", public, scim.Authenticate(scim.DeleteGroup))
 
-	router := http.NewServeMux()
-	router.HandleFunc("POST /auth/logout", auth.Logout)
-	router.HandleFunc("GET /auth/status", auth.GetStatus)
+	oidc := &OIDC{}
+	router.handle("GET /auth/oidc/login", public, oidc.Login)
+	router.handle("GET /auth/oidc/callback", public, oidc.Callback)
+
+	router.handle("POST /auth/logout", authenticated, auth.Logout)
+	router.handle("GET /auth/status", authenticated, auth.GetStatus)
+	router.handle("POST /auth/password", authenticated, auth.ChangePassword)
+
+	impersonation := &Impersonation{}
+	router.handle("DELETE /auth/impersonate", authenticated, impersonation.Stop)
+
+	twoFactor := &TwoFactor{}
+	router.handle("POST /auth/2fa/setup", authenticated, twoFactor.Setup)
+	router.handle("POST /auth/2fa/enable", authenticated, twoFactor.Enable)
+	router.handle("POST /auth/2fa/disable", authenticated, twoFactor.Disable)
+	router.handle("POST /auth/2fa/recovery-codes", authenticated, twoFactor.RegenerateRecoveryCodes)
+
+	router.handle("POST /auth/webauthn/register/begin", authenticated, webAuthn.BeginRegistration)
+	router.handle("POST /auth/webauthn/register/finish", authenticated, webAuthn.FinishRegistration)
+	router.handle("GET /auth/webauthn/credentials", authenticated, webAuthn.GetCredentials)
+	router.handle("DELETE /auth/webauthn/credentials/{id}", authenticated, webAuthn.DeleteCredential)
 
 	config := &Config{}
-	router.HandleFunc("GET /config", config.GetAll)
+	router.handle("GET /config", require(schema.PermissionReadBuckets), config.GetAll)
 
+	// Buckets are filtered by tenant and bucket grants, which also decide object access
 	buckets := &Buckets{}
-	router.HandleFunc("GET /buckets", buckets.GetAll)
+	router.handle("GET /buckets", authenticated, buckets.GetAll)
 
 	browse := &Browse{}
-	router.HandleFunc("GET /browse/{bucket}", browse.GetObjects)
-	router.HandleFunc("GET /browse/{bucket}/{key...}", browse.GetOneObject)
-	router.HandleFunc("PUT /browse/{bucket}/{key...}", browse.PutObject)
-	router.HandleFunc("DELETE /browse/{bucket}/{key...}", browse.DeleteObject)
+	router.handle("GET /browse/{bucket}", authenticated, browse.GetObjects)
+	router.handle("GET /browse/{bucket}/{key...}", authenticated, browse.GetOneObject)
+	router.handle("PUT /browse/{bucket}/{key...}", authenticated, browse.PutObject)
+	router.handle("DELETE /browse/{bucket}/{key...}", authenticated, browse.DeleteObject)
+
+	// Bucket grant routes
+	bucketGrants := &BucketGrants{}
+	router.handle("GET /buckets/{id}/grants", require(schema.PermissionReadBuckets), bucketGrants.GetAll)
+	router.handle("POST /buckets/{id}/grants", require(schema.PermissionWriteUsers), bucketGrants.Create)
+	router.handle("DELETE /buckets/{id}/grants/{grantId}", require(schema.PermissionWriteUsers), bucketGrants.Delete)
 
 	// User management routes
 	users := &Users{}
-	router.HandleFunc("GET /users", users.GetAll)
-	router.HandleFunc("GET /users/{id}", users.GetOne)
-	router.HandleFunc("POST /users", users.Create)
-	router.HandleFunc("PUT /users/{id}", users.Update)
-	router.HandleFunc("DELETE /users/{id}", users.Delete)
+	router.handle("GET /users", require(schema.PermissionReadUsers), users.GetAll)
+	router.handle("POST /users/invite", require(schema.PermissionWriteUsers), invitations.Create)
+	router.handle("GET /users/{id}", require(schema.PermissionReadUsers), users.GetOne)
+	router.handle("POST /users", require(schema.PermissionWriteUsers), users.Create)
+	router.handle("PUT /users/{id}", require(schema.PermissionWriteUsers), users.Update)
+	router.handle("DELETE /users/{id}", require(schema.PermissionDeleteUsers), users.Delete)
+	router.handle("DELETE /users/{id}/2fa", require(schema.PermissionWriteUsers), users.ResetTwoFactor)
+	router.handle("DELETE /users/{id}/lockout", require(schema.PermissionWriteUsers), users.Unlock)
+	router.handle("POST /users/{id}/password-reset", require(schema.PermissionWriteUsers), passwordReset.Create)
+	router.handle("POST /users/{id}/impersonate", require(schema.PermissionSystemAdmin), impersonation.Start)
+
+	// Role routes
+	roles := &Roles{}
+	router.handle("GET /roles", require(schema.PermissionReadUsers), roles.GetAll)
+	router.handle("GET /roles/{name}", require(schema.PermissionReadUsers), roles.GetOne)
+	router.handle("POST /roles", require(schema.PermissionSystemAdmin), roles.Create)
+	router.handle("PUT /roles/{name}", require(schema.PermissionSystemAdmin), roles.Update)
+	router.handle("DELETE /roles/{name}", require(schema.PermissionSystemAdmin), roles.Delete)
+
+	// Group routes
+	groups := &Groups{}
+	router.handle("GET /groups", require(schema.PermissionReadUsers), groups.GetAll)
+	router.handle("GET /groups/{id}", require(schema.PermissionReadUsers), groups.GetOne)
+	router.handle("POST /groups", require(schema.PermissionSystemAdmin), groups.Create)
+	router.handle("PUT /groups/{id}", require(schema.PermissionSystemAdmin), groups.Update)
+	router.handle("DELETE /groups/{id}", require(schema.PermissionSystemAdmin), groups.Delete)
+	router.handle("POST /groups/{id}/members", require(schema.PermissionSystemAdmin), groups.AddMembers)
+	router.handle("DELETE /groups/{id}/members/{userId}", require(schema.PermissionSystemAdmin), groups.RemoveMember)
+
+	// Personal access token routes, admins may manage the tokens of other users
+	tokens := &AccessTokens{}
+	router.handle("GET /tokens", authenticated, tokens.GetAll)
+	router.handle("POST /tokens", authenticated, tokens.Create)
+	router.handle("DELETE /tokens/{id}", authenticated, tokens.Delete)
 
 	// Tenant management routes
 	tenants := &Tenants{}
-	router.HandleFunc("GET /tenants", tenants.GetAll)
-	router.HandleFunc("GET /tenants/{id}", tenants.GetOne)
-	router.HandleFunc("POST /tenants", tenants.Create)
-	router.HandleFunc("PUT /tenants/{id}", tenants.Update)
-	router.HandleFunc("DELETE /tenants/{id}", tenants.Delete)
-	router.HandleFunc("GET /tenants/{id}/stats", tenants.GetStats)
+	router.handle("GET /tenants", require(schema.PermissionReadTenants), tenants.GetAll)
+	router.handle("GET /tenants/{id}", require(schema.PermissionReadTenants), tenants.GetOne)
+	router.handle("POST /tenants", require(schema.PermissionWriteTenants), tenants.Create)
+	router.handle("PUT /tenants/{id}", require(schema.PermissionWriteTenants), tenants.Update)
+	router.handle("DELETE /tenants/{id}", require(schema.PermissionDeleteTenants), tenants.Delete)
+	router.handle("GET /tenants/{id}/stats", require(schema.PermissionReadTenants), tenants.GetStats)
+	router.handle("PUT /buckets/{id}/tenant", require(schema.PermissionSystemAdmin), tenants.AssignBucket)
+	router.handle("PUT /keys/{id}/tenant", require(schema.PermissionSystemAdmin), tenants.AssignKey)
+
+	// Session routes, users manage their own sessions and admins those of other users
+	sessions := &Sessions{}
+	router.handle("GET /sessions", authenticated, sessions.GetAll)
+	router.handle("DELETE /sessions/{id}", authenticated, sessions.Delete)
+	router.handle("GET /users/{id}/sessions", authenticated, sessions.GetByUser)
+	router.handle("DELETE /users/{id}/sessions", authenticated, sessions.DeleteByUser)
+
+	// Privilege elevation routes
+	elevations := &Elevations{}
+	router.handle("GET /elevations", authenticated, elevations.GetAll)
+	router.handle("POST /elevations", authenticated, elevations.Create)
+	router.handle("POST /elevations/{id}/approve", require(schema.PermissionSystemAdmin), elevations.Approve)
+	router.handle("POST /elevations/{id}/deny", require(schema.PermissionSystemAdmin), elevations.Deny)
+	router.handle("DELETE /elevations/{id}", authenticated, elevations.Delete)
+
+	// Approval queue routes
+	approvals := &Approvals{}
+	router.handle("GET /approvals", authenticated, approvals.GetAll)
+	router.handle("POST /approvals/{id}/approve", require(schema.PermissionSystemAdmin), approvals.Approve)
+	router.handle("POST /approvals/{id}/deny", require(schema.PermissionSystemAdmin), approvals.Deny)
+	router.handle("DELETE /approvals/{id}", authenticated, approvals.Delete)
+
+	// Access simulator routes
+	authz := &Authz{}
+	router.handle("GET /authz/explain", require(schema.PermissionReadUsers), authz.Explain)
+
+	// Audit log routes
+	audit := &Audit{}
+	router.handle("GET /audit", require(schema.PermissionSystemAdmin), audit.GetAll)
+
+	// Security settings routes
+	settings := &Settings{}
+	router.handle("GET /settings/security", require(schema.PermissionSystemAdmin), settings.GetSecurity)
+	router.handle("PUT /settings/security", require(schema.PermissionSystemAdmin), settings.UpdateSecurity)
 
 	// S3 Configuration routes
 	s3config := &S3Config{}
-	router.HandleFunc("GET /s3/config", s3config.GetConfig)
-	router.HandleFunc("PUT /s3/config", s3config.UpdateConfig)
-	router.HandleFunc("POST /s3/test", s3config.TestConnection)
-	router.HandleFunc("GET /s3/status", s3config.GetStatus)
+	router.handle("GET /s3/config", require(schema.PermissionSystemAdmin), s3config.GetConfig)
+	router.handle("PUT /s3/config", require(schema.PermissionSystemAdmin), s3config.UpdateConfig)
+	router.handle("POST /s3/test", require(schema.PermissionSystemAdmin), s3config.TestConnection)
+	router.handle("GET /s3/status", require(schema.PermissionReadCluster), s3config.GetStatus)
 
 	// S3 Permissions routes
 	s3permissions := &S3Permissions{}
-	router.HandleFunc("GET /s3/policies/presets", s3permissions.GetPresetPolicies)
-	router.HandleFunc("POST /s3/policies/validate", s3permissions.ValidateS3Policy)
-	router.HandleFunc("GET /buckets/{bucketId}/keys/{accessKeyId}/permissions", s3permissions.GetKeyPermissions)
-	router.HandleFunc("PUT /buckets/{bucketId}/keys/{accessKeyId}/permissions", s3permissions.UpdateKeyPermissions)
+	router.handle("GET /s3/policies/presets", require(schema.PermissionReadKeys), s3permissions.GetPresetPolicies)
+	router.handle("POST /s3/policies/validate", require(schema.PermissionReadKeys), s3permissions.ValidateS3Policy)
+	router.handle("GET /buckets/{bucketId}/keys/{accessKeyId}/permissions", require(schema.PermissionReadKeys), s3permissions.GetKeyPermissions)
+	router.handle("PUT /buckets/{bucketId}/keys/{accessKeyId}/permissions", require(schema.PermissionWriteKeys), s3permissions.UpdateKeyPermissions)
 
 	// Object Locking routes
 	objectlocking := &ObjectLocking{}
-	router.HandleFunc("GET /buckets/{bucketId}/object-lock", objectlocking.GetBucketObjectLockConfiguration)
-	router.HandleFunc("PUT /buckets/{bucketId}/object-lock", objectlocking.PutBucketObjectLockConfiguration)
-	router.HandleFunc("GET /buckets/{bucketId}/objects", objectlocking.ListObjectsWithLocking)
-	router.HandleFunc("GET /buckets/{bucketId}/objects/{objectKey}/retention", objectlocking.GetObjectRetention)
-	router.HandleFunc("PUT /buckets/{bucketId}/objects/{objectKey}/retention", objectlocking.PutObjectRetention)
-	router.HandleFunc("GET /buckets/{bucketId}/objects/{objectKey}/legal-hold", objectlocking.GetObjectLegalHold)
-	router.HandleFunc("PUT /buckets/{bucketId}/objects/{objectKey}/legal-hold", objectlocking.PutObjectLegalHold)
-
-	// Proxy request to garage api endpoint
-	router.HandleFunc("/", ProxyHandler)
-
-	mux.Handle("/", middleware.AuthMiddleware(router))
-	return mux
+	router.handle("GET /buckets/{bucketId}/object-lock", require(schema.PermissionReadBuckets), objectlocking.GetBucketObjectLockConfiguration)
+	router.handle("PUT /buckets/{bucketId}/object-lock", require(schema.PermissionWriteBuckets), objectlocking.PutBucketObjectLockConfiguration)
+	router.handle("GET /buckets/{bucketId}/objects", require(schema.PermissionReadBuckets), objectlocking.ListObjectsWithLocking)
+	router.handle("GET /buckets/{bucketId}/objects/{objectKey}/retention", require(schema.PermissionReadBuckets), objectlocking.GetObjectRetention)
+	router.handle("PUT /buckets/{bucketId}/objects/{objectKey}/retention", require(schema.PermissionWriteBuckets), objectlocking.PutObjectRetention)
+	router.handle("GET /buckets/{bucketId}/objects/{objectKey}/legal-hold", require(schema.PermissionReadBuckets), objectlocking.GetObjectLegalHold)
+	router.handle("PUT /buckets/{bucketId}/objects/{objectKey}/legal-hold", require(schema.PermissionWriteBuckets), objectlocking.PutObjectLegalHold)
+
+	// Proxy request to garage api endpoint, with the permission of the requested endpoint
+	router.handle("/", middleware.RequireResolved(getGarageEndpointPermission), ProxyHandler)
+	return router
 }
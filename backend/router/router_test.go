@@ -0,0 +1,68 @@
+package router
+
+import (
+	"khairul169/garage-webui/middleware"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutesDeclareRequirement(t *testing.T) {
+	router := newApiRouter()
+	if len(router.routes) == 0 {
+		t.Fatal("no routes registered")
+	}
+	if err := router.checkRoutes(); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for _, route := range router.routes {
+		if !route.Requirement.IsSet() {
+			t.Errorf("route %q is registered without an access requirement", route.Pattern)
+		}
+		if seen[route.Pattern] {
+			t.Errorf("route %q is registered twice", route.Pattern)
+		}
+		seen[route.Pattern] = true
+	}
+}
+
+func TestCheckRoutesDetectsUndeclaredRoutes(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {}
+
+	router := newApiRouter()
+	router.private.HandleFunc("GET /undeclared", handler)
+	if err := router.checkRoutes(); err == nil {
+		t.Error("a route registered directly on the private mux was not detected")
+	}
+
+	router = newApiRouter()
+	router.public.HandleFunc("GET /users/{id}/undeclared", handler)
+	if err := router.checkRoutes(); err == nil {
+		t.Error("a route registered directly on the public mux was not detected")
+	}
+
+	router = newApiRouter()
+	router.public.HandleFunc("GET /users", handler)
+	if err := router.checkRoutes(); err == nil {
+		t.Error("a protected route also registered on the public mux was not detected")
+	}
+}
+
+func TestAuthorizeDeniesUnsetRequirement(t *testing.T) {
+	called := false
+	handler := middleware.Authorize(middleware.Requirement{}, func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if called {
+		t.Error("handler ran for a route without an access requirement")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
@@ -6,8 +6,6 @@ import (
 	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
 	"net/http"
-
-	"github.com/gorilla/mux"
 )
 
 type S3Permissions struct{}
@@ -35,16 +33,12 @@ type GetKeyPermissionsResponse struct {
 
 // GetKeyPermissions returns current permissions for a key
 func (sp *S3Permissions) GetKeyPermissions(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !sp.checkPermission(r, schema.PermissionReadKeys) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	accessKeyID := r.PathValue("accessKeyId")
+	if !checkResourceScope(w, r, bucketID, accessKeyID) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-	accessKeyID := vars["accessKeyId"]
-
 	// Get bucket info from Garage
 	body, err := utils.Garage.Fetch(fmt.Sprintf("/v2/GetBucketInfo?id=%s", bucketID), &utils.FetchOptions{})
 	if err != nil {
@@ -92,16 +86,12 @@ func (sp *S3Permissions) GetKeyPermissions(w http.ResponseWriter, r *http.Reques
 
 // UpdateKeyPermissions updates permissions for a key
 func (sp *S3Permissions) UpdateKeyPermissions(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !sp.checkPermission(r, schema.PermissionWriteKeys) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	bucketID := r.PathValue("bucketId")
+	accessKeyID := r.PathValue("accessKeyId")
+	if !checkResourceScope(w, r, bucketID, accessKeyID) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	bucketID := vars["bucketId"]
-	accessKeyID := vars["accessKeyId"]
-
 	var req UpdateKeyPermissionsRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -191,12 +181,6 @@ func (sp *S3Permissions) UpdateKeyPermissions(w http.ResponseWriter, r *http.Req
 
 // GetPresetPolicies returns available preset policies
 func (sp *S3Permissions) GetPresetPolicies(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !sp.checkPermission(r, schema.PermissionReadKeys) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	presets := schema.GetPresetPolicies()
 
 	// Convert to response format with descriptions
@@ -216,12 +200,6 @@ func (sp *S3Permissions) GetPresetPolicies(w http.ResponseWriter, r *http.Reques
 
 // ValidateS3Policy validates a custom S3 policy
 func (sp *S3Permissions) ValidateS3Policy(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !sp.checkPermission(r, schema.PermissionReadKeys) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	var policy schema.S3Policy
 	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
 		utils.ResponseErrorStatus(w, fmt.Errorf("invalid JSON: %v", err), http.StatusBadRequest)
@@ -313,18 +291,3 @@ func (sp *S3Permissions) getPolicyDescription(name string) string {
 	}
 	return "Custom policy"
 }
-
-// checkPermission checks if user has required permission
-func (sp *S3Permissions) checkPermission(r *http.Request, permission schema.Permission) bool {
-	userID := utils.Session.Get(r, "user_id")
-	if userID == nil {
-		return false
-	}
-
-	user, err := utils.DB.GetUser(userID.(string))
-	if err != nil {
-		return false
-	}
-
-	return user.HasPermission(permission)
-}
\ No newline at end of file
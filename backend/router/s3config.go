@@ -2,7 +2,6 @@ package router
 
 import (
 	"encoding/json"
-	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
 	"net/http"
 )
@@ -28,12 +27,6 @@ type UpdateS3ConfigRequest struct {
 }
 
 func (s *S3Config) GetConfig(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !s.checkPermission(r, schema.PermissionSystemAdmin) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	response := S3ConfigResponse{
 		Region:      utils.Garage.GetS3Region(),
 		Endpoint:    utils.Garage.GetS3Endpoint(),
@@ -46,12 +39,6 @@ func (s *S3Config) GetConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *S3Config) UpdateConfig(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !s.checkPermission(r, schema.PermissionSystemAdmin) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	var req UpdateS3ConfigRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -90,12 +77,6 @@ func (s *S3Config) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *S3Config) TestConnection(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !s.checkPermission(r, schema.PermissionSystemAdmin) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	// Test Garage API connection
 	_, err := utils.Garage.Fetch("/status", &utils.FetchOptions{
 		Method: "GET",
@@ -113,12 +94,6 @@ func (s *S3Config) TestConnection(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *S3Config) GetStatus(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !s.checkPermission(r, schema.PermissionReadCluster) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	// Get status from Garage API
 	data, err := utils.Garage.Fetch("/status", &utils.FetchOptions{
 		Method: "GET",
@@ -148,17 +123,3 @@ func (s *S3Config) GetStatus(w http.ResponseWriter, r *http.Request) {
 
 	utils.ResponseSuccess(w, response)
 }
-
-func (s *S3Config) checkPermission(r *http.Request, permission schema.Permission) bool {
-	userID := utils.Session.Get(r, "user_id")
-	if userID == nil {
-		return false
-	}
-
-	user, err := utils.DB.GetUser(userID.(string))
-	if err != nil {
-		return false
-	}
-
-	return user.HasPermission(permission)
-}
\ No newline at end of file
@@ -0,0 +1,729 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// SCIM implements the SCIM 2.0 provisioning protocol (RFC 7644) for users and groups
+type SCIM struct{}
+
+// Authenticate guards the SCIM endpoints with the dedicated SCIM_TOKEN
+func (s *SCIM) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !utils.SCIM.Enabled() {
+			s.error(w, http.StatusNotFound, "SCIM provisioning is not enabled")
+			return
+		}
+		if !utils.SCIM.Authenticate(r) {
+			s.error(w, http.StatusUnauthorized, "invalid SCIM token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *SCIM) GetServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	s.respond(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{schema.SCIMSchemaServiceConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": 1000},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Authentication with the SCIM_TOKEN",
+		}},
+	})
+}
+
+// Users
+
+func (s *SCIM) GetUsers(w http.ResponseWriter, r *http.Request) {
+	filter, err := utils.ParseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, err := utils.DB.ListUsers()
+	if err != nil {
+		s.error(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	resources := []interface{}{}
+	for _, user := range users {
+		resource := s.toSCIMUser(r, user)
+		if filter.Match(func(attribute string) []string { return s.userAttribute(resource, attribute) }) {
+			resources = append(resources, resource)
+		}
+	}
+
+	s.respondList(w, r, resources)
+}
+
+func (s *SCIM) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := utils.DB.GetUser(r.PathValue("id"))
+	if err != nil {
+		s.error(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	s.respond(w, http.StatusOK, s.toSCIMUser(r, user))
+}
+
+func (s *SCIM) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var body schema.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.UserName == "" {
+		s.error(w, http.StatusBadRequest, "userName is required")
+		return
+	}
+
+	active := body.Active == nil || *body.Active
+	user, err := utils.DB.CreateSCIMUser(body.UserName, s.primaryEmail(body.Emails), body.ExternalID, body.Password, active)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(user.ID); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.respondUser(w, r, user.ID, http.StatusCreated)
+}
+
+func (s *SCIM) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	var body schema.SCIMUser
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.UserName == "" {
+		s.error(w, http.StatusBadRequest, "userName is required")
+		return
+	}
+
+	email := s.primaryEmail(body.Emails)
+	active := body.Active == nil || *body.Active
+	req := &schema.UpdateUserRequest{
+		Username: &body.UserName,
+		Email:    &email,
+		Enabled:  &active,
+	}
+	if body.Password != "" {
+		req.Password = &body.Password
+	}
+
+	s.updateUser(w, r, userID, req, &body.ExternalID)
+}
+
+func (s *SCIM) PatchUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+
+	var body schema.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	req := &schema.UpdateUserRequest{}
+	var externalID *string
+
+	apply := func(op, path string, value json.RawMessage) error {
+		switch strings.ToLower(path) {
+		case "active":
+			active, err := s.parseBool(value)
+			if err != nil {
+				return err
+			}
+			req.Enabled = &active
+		case "username":
+			var username string
+			if err := json.Unmarshal(value, &username); err != nil {
+				return err
+			}
+			req.Username = &username
+		case "externalid":
+			var id string
+			if op != "remove" {
+				if err := json.Unmarshal(value, &id); err != nil {
+					return err
+				}
+			}
+			externalID = &id
+		case "password":
+			var password string
+			if err := json.Unmarshal(value, &password); err != nil {
+				return err
+			}
+			req.Password = &password
+		default:
+			if strings.HasPrefix(strings.ToLower(path), "emails") {
+				email := ""
+				if op != "remove" {
+					email = s.parseEmail(value)
+				}
+				req.Email = &email
+			}
+			// Other attributes such as name or title are not stored
+		}
+		return nil
+	}
+
+	for _, operation := range body.Operations {
+		op := strings.ToLower(operation.Op)
+		if op != "add" && op != "replace" && op != "remove" {
+			s.error(w, http.StatusBadRequest, "unsupported patch operation: "+operation.Op)
+			return
+		}
+
+		if operation.Path != "" {
+			if err := apply(op, operation.Path, operation.Value); err != nil {
+				s.error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			continue
+		}
+
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(operation.Value, &values); err != nil {
+			s.error(w, http.StatusBadRequest, "patch value must be an object when no path is given")
+			return
+		}
+		for path, value := range values {
+			if err := apply(op, path, value); err != nil {
+				s.error(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	s.updateUser(w, r, userID, req, externalID)
+}
+
+func (s *SCIM) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := s.getProvisionedUser(w, r.PathValue("id"))
+	if !ok {
+		return
+	}
+
+	if err := utils.DB.DeleteUser(user.ID); err != nil {
+		s.error(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *SCIM) updateUser(w http.ResponseWriter, r *http.Request, userID string, req *schema.UpdateUserRequest, externalID *string) {
+	if _, ok := s.getProvisionedUser(w, userID); !ok {
+		return
+	}
+
+	if req.Username != nil {
+		if existing, err := utils.DB.GetUserByUsername(*req.Username); err == nil && existing.ID != userID {
+			s.writeError(w, utils.ErrSCIMConflict)
+			return
+		}
+	}
+
+	if _, err := utils.DB.UpdateUser(userID, req); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if externalID != nil {
+		if err := utils.DB.SetSCIMExternalID(userID, *externalID); err != nil {
+			s.writeError(w, err)
+			return
+		}
+	}
+
+	if err := utils.DB.SyncSCIMUsers(userID); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.respondUser(w, r, userID, http.StatusOK)
+}
+
+// getProvisionedUser loads a user created through SCIM. Local, SSO and LDAP accounts
+// are reported as not found so SCIM clients cannot change or delete them.
+func (s *SCIM) getProvisionedUser(w http.ResponseWriter, userID string) (*schema.User, bool) {
+	user, err := utils.DB.GetUser(userID)
+	if err != nil || !user.SCIMProvisioned {
+		s.error(w, http.StatusNotFound, "user not found")
+		return nil, false
+	}
+	return user, true
+}
+
+func (s *SCIM) respondUser(w http.ResponseWriter, r *http.Request, userID string, status int) {
+	user, err := utils.DB.GetUser(userID)
+	if err != nil {
+		s.error(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	s.respond(w, status, s.toSCIMUser(r, user))
+}
+
+func (s *SCIM) toSCIMUser(r *http.Request, user *schema.User) schema.SCIMUser {
+	active := user.Enabled
+	resource := schema.SCIMUser{
+		Schemas:     []string{schema.SCIMSchemaUser},
+		ID:          user.ID,
+		ExternalID:  user.SCIMExternalID,
+		UserName:    user.Username,
+		DisplayName: user.Username,
+		Active:      &active,
+		Meta: &schema.SCIMMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     s.baseURL(r) + "/Users/" + user.ID,
+		},
+	}
+
+	if user.Email != "" {
+		resource.Emails = []schema.SCIMMultiValue{{Value: user.Email, Type: "work", Primary: true}}
+	}
+
+	for _, group := range utils.DB.GetUserGroups(user.ID) {
+		resource.Groups = append(resource.Groups, schema.SCIMMember{
+			Value:   group.ID,
+			Display: group.Name,
+			Ref:     s.baseURL(r) + "/Groups/" + group.ID,
+		})
+	}
+
+	return resource
+}
+
+func (s *SCIM) userAttribute(user schema.SCIMUser, attribute string) []string {
+	switch attribute {
+	case "id":
+		return []string{user.ID}
+	case "username":
+		return []string{user.UserName}
+	case "externalid":
+		return []string{user.ExternalID}
+	case "displayname":
+		return []string{user.DisplayName}
+	case "active":
+		return []string{strconv.FormatBool(user.Active != nil && *user.Active)}
+	case "emails", "emails.value":
+		values := []string{}
+		for _, email := range user.Emails {
+			values = append(values, email.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+// Groups
+
+func (s *SCIM) GetGroups(w http.ResponseWriter, r *http.Request) {
+	filter, err := utils.ParseSCIMFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resources := []interface{}{}
+	for _, group := range utils.DB.ListGroups() {
+		resource := s.toSCIMGroup(r, group)
+		if filter.Match(func(attribute string) []string { return s.groupAttribute(resource, attribute) }) {
+			resources = append(resources, resource)
+		}
+	}
+
+	s.respondList(w, r, resources)
+}
+
+func (s *SCIM) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := utils.DB.GetGroup(r.PathValue("id"))
+	if err != nil {
+		s.error(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	s.respond(w, http.StatusOK, s.toSCIMGroup(r, group))
+}
+
+func (s *SCIM) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var body schema.SCIMGroup
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.DisplayName == "" {
+		s.error(w, http.StatusBadRequest, "displayName is required")
+		return
+	}
+
+	group, err := utils.DB.CreateGroup(&schema.Group{
+		Name:       body.DisplayName,
+		ExternalID: body.ExternalID,
+		Members:    s.memberIDs(body.Members),
+	})
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(group.Members...); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.respond(w, http.StatusCreated, s.toSCIMGroup(r, group))
+}
+
+func (s *SCIM) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	var body schema.SCIMGroup
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if body.DisplayName == "" {
+		s.error(w, http.StatusBadRequest, "displayName is required")
+		return
+	}
+
+	s.updateGroup(w, r, func(group *schema.Group) error {
+		group.Name = body.DisplayName
+		group.ExternalID = body.ExternalID
+		group.Members = s.memberIDs(body.Members)
+		return nil
+	})
+}
+
+func (s *SCIM) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	var body schema.SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		s.error(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.updateGroup(w, r, func(group *schema.Group) error {
+		for _, operation := range body.Operations {
+			op := strings.ToLower(operation.Op)
+			if op != "add" && op != "replace" && op != "remove" {
+				return fmt.Errorf("unsupported patch operation: %s", operation.Op)
+			}
+
+			if operation.Path != "" {
+				if err := s.patchGroupPath(group, op, operation.Path, operation.Value); err != nil {
+					return err
+				}
+				continue
+			}
+
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &values); err != nil {
+				return errors.New("patch value must be an object when no path is given")
+			}
+			for path, value := range values {
+				if err := s.patchGroupPath(group, op, path, value); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SCIM) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	members, err := utils.DB.DeleteGroup(r.PathValue("id"))
+	if err != nil {
+		s.error(w, http.StatusNotFound, "group not found")
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(members...); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *SCIM) patchGroupPath(group *schema.Group, op, path string, value json.RawMessage) error {
+	lowerPath := strings.ToLower(path)
+
+	switch {
+	case lowerPath == "displayname":
+		if op == "remove" {
+			return errors.New("displayName is required")
+		}
+		return json.Unmarshal(value, &group.Name)
+
+	case lowerPath == "externalid":
+		if op == "remove" {
+			group.ExternalID = ""
+			return nil
+		}
+		return json.Unmarshal(value, &group.ExternalID)
+
+	case lowerPath == "members":
+		var members []schema.SCIMMember
+		if len(value) > 0 {
+			if err := json.Unmarshal(value, &members); err != nil {
+				return err
+			}
+		}
+		ids := s.memberIDs(members)
+
+		switch op {
+		case "add":
+			group.Members = append(group.Members, ids...)
+		case "replace":
+			group.Members = ids
+		case "remove":
+			if len(value) == 0 {
+				group.Members = nil
+			} else {
+				group.Members = removeStrings(group.Members, ids)
+			}
+		}
+		return nil
+
+	case strings.HasPrefix(lowerPath, "members["):
+		// e.g. members[value eq "2819c223-7f76-453a-919d-413861904646"]
+		if op != "remove" {
+			return fmt.Errorf("unsupported patch path for %s: %s", op, path)
+		}
+		filter, err := utils.ParseSCIMFilter(strings.TrimSuffix(path[len("members["):], "]"))
+		if err != nil {
+			return err
+		}
+
+		members := []string{}
+		for _, member := range group.Members {
+			if !filter.Match(func(string) []string { return []string{member} }) {
+				members = append(members, member)
+			}
+		}
+		group.Members = members
+		return nil
+	}
+
+	return fmt.Errorf("unsupported patch path: %s", path)
+}
+
+func (s *SCIM) updateGroup(w http.ResponseWriter, r *http.Request, update func(group *schema.Group) error) {
+	group, changed, err := utils.DB.UpdateGroup(r.PathValue("id"), update)
+	if err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	if err := utils.DB.SyncSCIMUsers(changed...); err != nil {
+		s.writeError(w, err)
+		return
+	}
+
+	s.respond(w, http.StatusOK, s.toSCIMGroup(r, group))
+}
+
+func (s *SCIM) toSCIMGroup(r *http.Request, group *schema.Group) schema.SCIMGroup {
+	resource := schema.SCIMGroup{
+		Schemas:     []string{schema.SCIMSchemaGroup},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.Name,
+		Members:     []schema.SCIMMember{},
+		Meta: &schema.SCIMMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     s.baseURL(r) + "/Groups/" + group.ID,
+		},
+	}
+
+	if strings.Contains(r.URL.Query().Get("excludedAttributes"), "members") {
+		return resource
+	}
+
+	for _, member := range group.Members {
+		entry := schema.SCIMMember{Value: member, Ref: s.baseURL(r) + "/Users/" + member}
+		if user, err := utils.DB.GetUser(member); err == nil {
+			entry.Display = user.Username
+		}
+		resource.Members = append(resource.Members, entry)
+	}
+
+	return resource
+}
+
+func (s *SCIM) groupAttribute(group schema.SCIMGroup, attribute string) []string {
+	switch attribute {
+	case "id":
+		return []string{group.ID}
+	case "displayname":
+		return []string{group.DisplayName}
+	case "externalid":
+		return []string{group.ExternalID}
+	case "members", "members.value":
+		values := []string{}
+		for _, member := range group.Members {
+			values = append(values, member.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+// Helpers
+
+func (s *SCIM) respondList(w http.ResponseWriter, r *http.Request, resources []interface{}) {
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 0 {
+		count = 100
+	}
+
+	total := len(resources)
+	start := min(startIndex-1, total)
+	end := min(start+count, total)
+
+	s.respond(w, http.StatusOK, schema.SCIMListResponse{
+		Schemas:      []string{schema.SCIMSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: end - start,
+		Resources:    resources[start:end],
+	})
+}
+
+func (s *SCIM) respond(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(data)
+}
+
+func (s *SCIM) error(w http.ResponseWriter, status int, detail string) {
+	s.respond(w, status, schema.SCIMError{
+		Schemas: []string{schema.SCIMSchemaError},
+		Status:  strconv.Itoa(status),
+		Detail:  detail,
+	})
+}
+
+// writeError maps database errors to SCIM error responses
+func (s *SCIM) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, utils.ErrSCIMConflict):
+		s.respond(w, http.StatusConflict, schema.SCIMError{
+			Schemas:  []string{schema.SCIMSchemaError},
+			Status:   strconv.Itoa(http.StatusConflict),
+			SCIMType: "uniqueness",
+			Detail:   err.Error(),
+		})
+	case strings.HasSuffix(err.Error(), "not found"):
+		s.error(w, http.StatusNotFound, err.Error())
+	default:
+		s.error(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+func (s *SCIM) baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s%s/api/scim/v2", scheme, r.Host, os.Getenv("BASE_PATH"))
+}
+
+func (s *SCIM) primaryEmail(emails []schema.SCIMMultiValue) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+// parseEmail accepts a plain address or a list of SCIM email values
+func (s *SCIM) parseEmail(value json.RawMessage) string {
+	var email string
+	if err := json.Unmarshal(value, &email); err == nil {
+		return email
+	}
+
+	var emails []schema.SCIMMultiValue
+	if err := json.Unmarshal(value, &emails); err == nil {
+		return s.primaryEmail(emails)
+	}
+	return ""
+}
+
+// parseBool accepts JSON booleans as well as the "True"/"False" strings some providers send
+func (s *SCIM) parseBool(value json.RawMessage) (bool, error) {
+	var result bool
+	if err := json.Unmarshal(value, &result); err == nil {
+		return result, nil
+	}
+
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return false, errors.New("invalid boolean value")
+	}
+	return strconv.ParseBool(strings.ToLower(text))
+}
+
+func (s *SCIM) memberIDs(members []schema.SCIMMember) []string {
+	ids := make([]string, 0, len(members))
+	for _, member := range members {
+		ids = append(ids, member.Value)
+	}
+	return ids
+}
+
+func removeStrings(values, remove []string) []string {
+	result := []string{}
+	for _, value := range values {
+		keep := true
+		for _, r := range remove {
+			if value == r {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			result = append(result, value)
+		}
+	}
+	return result
+}
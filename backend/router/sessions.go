@@ -0,0 +1,138 @@
+package router
+
+import (
+	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+)
+
+type Sessions struct{}
+
+// GetAll lists the sessions of the current user, or of all users for admins
+func (s *Sessions) GetAll(w http.ResponseWriter, r *http.Request) {
+	userID := utils.GetAuthUserID(r)
+
+	if r.URL.Query().Get("all") == "true" {
+		if !middleware.HasPermission(r, schema.PermissionReadUsers) {
+			utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+			return
+		}
+		userID = ""
+	}
+
+	s.respondSessions(w, r, userID)
+}
+
+// GetByUser lists the sessions of a specific user
+func (s *Sessions) GetByUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	if userID != utils.GetAuthUserID(r) && !middleware.HasPermission(r, schema.PermissionReadUsers) {
+		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+		return
+	}
+
+	if _, ok := getScopedUser(w, r, userID); !ok {
+		return
+	}
+
+	s.respondSessions(w, r, userID)
+}
+
+// Delete revokes a single session
+func (s *Sessions) Delete(w http.ResponseWriter, r *http.Request) {
+	sessionID := r.PathValue("id")
+	if sessionID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	session, err := utils.DB.GetSession(sessionID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Users may revoke their own sessions, admins may revoke any session in their tenant
+	if session.UserID != utils.GetAuthUserID(r) && !middleware.HasPermission(r, schema.PermissionWriteUsers) {
+		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+		return
+	}
+	if _, ok := getScopedUser(w, r, session.UserID); !ok {
+		return
+	}
+
+	if err := utils.DB.DeleteSession(sessionID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	if auth := utils.GetAuth(r); auth != nil && auth.SessionID == sessionID {
+		utils.Session.Destroy(r)
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// DeleteByUser revokes all sessions of a user
+func (s *Sessions) DeleteByUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	if userID != utils.GetAuthUserID(r) && !middleware.HasPermission(r, schema.PermissionWriteUsers) {
+		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+		return
+	}
+
+	if _, ok := getScopedUser(w, r, userID); !ok {
+		return
+	}
+
+	if err := utils.DB.DeleteUserSessions(userID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+func (s *Sessions) respondSessions(w http.ResponseWriter, r *http.Request, userID string) {
+	sessions, err := utils.DB.ListSessions(userID)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	// Only list the sessions of users in the caller's tenant
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+	visible := sessions[:0]
+	for _, session := range sessions {
+		if user, err := utils.DB.GetUser(session.UserID); err == nil && utils.DB.CanAccessUser(actor, user) {
+			visible = append(visible, session)
+		}
+	}
+
+	// Mark the session the request was made with
+	auth := utils.GetAuth(r)
+	result := make([]schema.SessionResponse, 0, len(visible))
+	for _, session := range visible {
+		result = append(result, schema.SessionResponse{
+			Session: session,
+			Current: auth != nil && session.ID == auth.SessionID,
+		})
+	}
+
+	utils.ResponseSuccess(w, result)
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+)
+
+type Settings struct{}
+
+func (s *Settings) GetSecurity(w http.ResponseWriter, r *http.Request) {
+	utils.ResponseSuccess(w, utils.DB.GetSecuritySettings())
+}
+
+func (s *Settings) UpdateSecurity(w http.ResponseWriter, r *http.Request) {
+	var req schema.SecuritySettings
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	for _, role := range req.RequireTwoFactorRoles {
+		if !utils.DB.RoleExists(role) {
+			utils.ResponseErrorStatus(w, fmt.Errorf("unknown role: %s", role), http.StatusBadRequest)
+			return
+		}
+	}
+
+	for _, op := range req.ApprovalOperations {
+		if _, ok := garageEndpointPermissions[op]; !ok && op != schema.ApprovalOperationDeleteDirectory {
+			utils.ResponseErrorStatus(w, fmt.Errorf("unknown operation: %s", op), http.StatusBadRequest)
+			return
+		}
+	}
+
+	settings, err := utils.DB.UpdateSecuritySettings(&req)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, settings)
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"encoding/json"
+	"fmt"
 	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
 	"net/http"
@@ -10,12 +11,6 @@ import (
 type Tenants struct{}
 
 func (t *Tenants) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !t.checkPermission(r, schema.PermissionReadTenants) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	tenants, err := utils.DB.ListTenants()
 	if err != nil {
 		utils.ResponseError(w, err)
@@ -32,12 +27,6 @@ func (t *Tenants) GetOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !t.checkPermission(r, schema.PermissionReadTenants) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	tenant, err := utils.DB.GetTenant(tenantID)
 	if err != nil {
 		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
@@ -48,12 +37,6 @@ func (t *Tenants) GetOne(w http.ResponseWriter, r *http.Request) {
 }
 
 func (t *Tenants) Create(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !t.checkPermission(r, schema.PermissionWriteTenants) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	var req schema.CreateTenantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -82,12 +65,6 @@ func (t *Tenants) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !t.checkPermission(r, schema.PermissionWriteTenants) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	var req schema.UpdateTenantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -110,12 +87,6 @@ func (t *Tenants) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !t.checkPermission(r, schema.PermissionDeleteTenants) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	err := utils.DB.DeleteTenant(tenantID)
 	if err != nil {
 		utils.ResponseError(w, err)
@@ -132,12 +103,6 @@ func (t *Tenants) GetStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !t.checkPermission(r, schema.PermissionReadTenants) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	// Get tenant
 	tenant, err := utils.DB.GetTenant(tenantID)
 	if err != nil {
@@ -159,18 +124,44 @@ func (t *Tenants) GetStats(w http.ResponseWriter, r *http.Request) {
 	utils.ResponseSuccess(w, stats)
 }
 
-func (t *Tenants) checkPermission(r *http.Request, permission schema.Permission) bool {
-	userID := utils.Session.Get(r, "user_id")
-	if userID == nil {
-		return false
+// AssignBucket moves a bucket to a tenant or back to the shared pool
+func (t *Tenants) AssignBucket(w http.ResponseWriter, r *http.Request) {
+	t.assignResource(w, r, "bucket", utils.DB.SetBucketTenant)
+}
+
+// AssignKey moves an access key to a tenant or back to the shared pool
+func (t *Tenants) AssignKey(w http.ResponseWriter, r *http.Request) {
+	t.assignResource(w, r, "key", utils.DB.SetKeyTenant)
+}
+
+func (t *Tenants) assignResource(w http.ResponseWriter, r *http.Request, kind string, assign func(id, tenantID, assignedBy string) error) {
+	resourceID := r.PathValue("id")
+	if resourceID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
 	}
 
-	user, err := utils.DB.GetUser(userID.(string))
-	if err != nil {
-		return false
+	var req schema.AssignTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
 	}
 
-	return user.HasPermission(permission)
+	tenantID := ""
+	if req.TenantID != nil {
+		tenantID = *req.TenantID
+	}
+
+	if err := assign(resourceID, tenantID, utils.GetAuthUserID(r)); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditTenantAssigned)
+	event.Message = fmt.Sprintf("%s %s assigned to tenant %q", kind, resourceID, tenantID)
+	utils.DB.RecordAudit(event)
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
 }
 
 func (t *Tenants) getUserCountForTenant(tenantID string) int {
@@ -181,10 +172,10 @@ func (t *Tenants) getUserCountForTenant(tenantID string) int {
 
 	count := 0
 	for _, user := range users {
-		if user.TenantID != nil && *user.TenantID == tenantID {
+		if userTenantID := utils.DB.GetUserTenantID(user); userTenantID != nil && *userTenantID == tenantID {
 			count++
 		}
 	}
 
 	return count
-}
\ No newline at end of file
+}
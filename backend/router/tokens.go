@@ -0,0 +1,137 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"time"
+)
+
+type AccessTokens struct{}
+
+func (t *AccessTokens) GetAll(w http.ResponseWriter, r *http.Request) {
+	userID := utils.GetAuthUserID(r)
+
+	// Admins may list the tokens of another user
+	if target := r.URL.Query().Get("user_id"); target != "" && target != userID {
+		if !middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+			utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+			return
+		}
+		userID = target
+	}
+
+	tokens, err := utils.DB.ListAccessTokens(userID)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, tokens)
+}
+
+func (t *AccessTokens) Create(w http.ResponseWriter, r *http.Request) {
+	if !t.checkSessionAuth(w, r) {
+		return
+	}
+
+	var req schema.CreateAccessTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	// Validate request
+	if req.Name == "" {
+		utils.ResponseErrorStatus(w, errors.New("name is required"), http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		utils.ResponseErrorStatus(w, errors.New("at least one scope is required"), http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		utils.ResponseErrorStatus(w, errors.New("expiry must be in the future"), http.StatusBadRequest)
+		return
+	}
+
+	// Admins may create tokens for automation accounts
+	userID := utils.GetAuthUserID(r)
+	if req.UserID != nil && *req.UserID != userID {
+		if !middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+			utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+			return
+		}
+		userID = *req.UserID
+	}
+
+	user, err := utils.DB.GetUser(userID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Tokens can never grant more than their owner has
+	for _, scope := range req.Scopes {
+		if !scope.IsValid() {
+			utils.ResponseErrorStatus(w, fmt.Errorf("unknown scope: %s", scope), http.StatusBadRequest)
+			return
+		}
+		if !utils.DB.HasPermission(user, scope) {
+			utils.ResponseErrorStatus(w, fmt.Errorf("user does not have permission: %s", scope), http.StatusBadRequest)
+			return
+		}
+	}
+
+	token, err := utils.DB.CreateAccessToken(user.ID, &req)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, token)
+}
+
+func (t *AccessTokens) Delete(w http.ResponseWriter, r *http.Request) {
+	tokenID := r.PathValue("id")
+	if tokenID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	if !t.checkSessionAuth(w, r) {
+		return
+	}
+
+	token, err := utils.DB.GetAccessToken(tokenID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Only the owner or an admin may revoke a token
+	if token.UserID != utils.GetAuthUserID(r) && !middleware.HasPermission(r, schema.PermissionSystemAdmin) {
+		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+		return
+	}
+
+	if err := utils.DB.DeleteAccessToken(tokenID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// checkSessionAuth rejects token management requests made with an access token
+func (t *AccessTokens) checkSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if auth := utils.GetAuth(r); auth != nil && auth.Method == utils.AuthMethodToken {
+		utils.ResponseErrorStatus(w, errors.New("access tokens cannot manage access tokens"), http.StatusForbidden)
+		return false
+	}
+	return true
+}
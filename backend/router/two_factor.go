@@ -0,0 +1,138 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const totpIssuer = "Garage Web UI"
+
+type TwoFactor struct{}
+
+// Setup generates a new TOTP secret to be confirmed with Enable
+func (t *TwoFactor) Setup(w http.ResponseWriter, r *http.Request) {
+	if !t.checkSessionAuth(w, r) {
+		return
+	}
+
+	user, err := utils.DB.GetUser(utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	secret, err := utils.DB.BeginTwoFactorSetup(user.ID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseSuccess(w, schema.TwoFactorSetupResponse{
+		Secret: secret,
+		URI:    utils.TOTPURI(totpIssuer, user.Username, secret),
+	})
+}
+
+// Enable confirms the pending secret and returns the recovery codes
+func (t *TwoFactor) Enable(w http.ResponseWriter, r *http.Request) {
+	if !t.checkSessionAuth(w, r) {
+		return
+	}
+
+	var req schema.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	codes, err := utils.DB.EnableTwoFactor(utils.GetAuthUserID(r), req.Code)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseSuccess(w, schema.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable removes 2FA from the current user after re-checking both factors
+func (t *TwoFactor) Disable(w http.ResponseWriter, r *http.Request) {
+	if !t.checkSessionAuth(w, r) {
+		return
+	}
+
+	var req schema.TwoFactorDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	user, err := utils.DB.GetUser(utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	settings := utils.DB.GetSecuritySettings()
+	if settings.RequiresTwoFactor(user.Role) {
+		utils.ResponseErrorStatus(w, errors.New("two-factor authentication is required for your role"), http.StatusForbidden)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		utils.ResponseErrorStatus(w, errors.New("invalid credentials"), http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.DB.VerifyTwoFactor(user.ID, req.Code); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	if err := utils.DB.DisableTwoFactor(user.ID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (t *TwoFactor) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	if !t.checkSessionAuth(w, r) {
+		return
+	}
+
+	var req schema.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	userID := utils.GetAuthUserID(r)
+	if err := utils.DB.VerifyTwoFactor(userID, req.Code); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	codes, err := utils.DB.RegenerateRecoveryCodes(userID)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, schema.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// checkSessionAuth rejects two-factor changes made with an access token
+func (t *TwoFactor) checkSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if auth := utils.GetAuth(r); auth != nil && auth.Method == utils.AuthMethodToken {
+		utils.ResponseErrorStatus(w, errors.New("access tokens cannot manage two-factor authentication"), http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package router
+
+import (
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTwoFactorRejectsAccessTokens(t *testing.T) {
+	twoFactor := &TwoFactor{}
+	handlers := map[string]http.HandlerFunc{
+		"setup":          twoFactor.Setup,
+		"enable":         twoFactor.Enable,
+		"disable":        twoFactor.Disable,
+		"recovery-codes": twoFactor.RegenerateRecoveryCodes,
+	}
+
+	for name, handler := range handlers {
+		r := httptest.NewRequest(http.MethodPost, "/auth/2fa/"+name, strings.NewReader(`{"code":"123456","password":"secret"}`))
+		r = utils.WithAuth(r, &utils.AuthInfo{UserID: "owner", Method: utils.AuthMethodToken})
+
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s with an access token: status = %d, want %d", name, w.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"encoding/json"
+	"errors"
 	"khairul169/garage-webui/schema"
 	"khairul169/garage-webui/utils"
 	"net/http"
@@ -10,9 +11,9 @@ import (
 type Users struct{}
 
 func (u *Users) GetAll(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !u.checkPermission(r, schema.PermissionReadUsers) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
 		return
 	}
 
@@ -22,7 +23,7 @@ func (u *Users) GetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.ResponseSuccess(w, users)
+	utils.ResponseSuccess(w, utils.DB.FilterUsers(actor, users))
 }
 
 func (u *Users) GetOne(w http.ResponseWriter, r *http.Request) {
@@ -32,15 +33,8 @@ func (u *Users) GetOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !u.checkPermission(r, schema.PermissionReadUsers) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
-	user, err := utils.DB.GetUser(userID)
-	if err != nil {
-		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+	user, ok := getScopedUser(w, r, userID)
+	if !ok {
 		return
 	}
 
@@ -48,12 +42,6 @@ func (u *Users) GetOne(w http.ResponseWriter, r *http.Request) {
 }
 
 func (u *Users) Create(w http.ResponseWriter, r *http.Request) {
-	// Check permissions
-	if !u.checkPermission(r, schema.PermissionWriteUsers) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	var req schema.CreateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
@@ -66,7 +54,26 @@ func (u *Users) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	// Tenant admins create users in their own tenant
+	if tenantID, scoped := utils.DB.GetUserScope(actor); scoped && req.TenantID == nil && tenantID != "" {
+		req.TenantID = &tenantID
+	}
+	if err := utils.DB.CheckUserAssignment(actor, &req.Role, req.TenantID); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusForbidden)
+		return
+	}
+
 	user, err := utils.DB.CreateUser(&req)
+	if errors.Is(err, utils.ErrWeakPassword) || errors.Is(err, utils.ErrRoleNotFound) {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		utils.ResponseError(w, err)
 		return
@@ -82,19 +89,31 @@ func (u *Users) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !u.checkPermission(r, schema.PermissionWriteUsers) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
-		return
-	}
-
 	var req schema.UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.ResponseError(w, err)
 		return
 	}
 
+	if _, ok := getScopedUser(w, r, userID); !ok {
+		return
+	}
+
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+	if err := utils.DB.CheckUserAssignment(actor, req.Role, req.TenantID); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusForbidden)
+		return
+	}
+
 	user, err := utils.DB.UpdateUser(userID, &req)
+	if errors.Is(err, utils.ErrWeakPassword) || errors.Is(err, utils.ErrRoleNotFound) {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		utils.ResponseError(w, err)
 		return
@@ -110,16 +129,13 @@ func (u *Users) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check permissions
-	if !u.checkPermission(r, schema.PermissionDeleteUsers) {
-		utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+	// Prevent self-deletion
+	if utils.GetAuthUserID(r) == userID {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
 		return
 	}
 
-	// Prevent self-deletion
-	currentUserID := utils.Session.Get(r, "user_id")
-	if currentUserID != nil && currentUserID.(string) == userID {
-		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+	if _, ok := getScopedUser(w, r, userID); !ok {
 		return
 	}
 
@@ -132,16 +148,70 @@ func (u *Users) Delete(w http.ResponseWriter, r *http.Request) {
 	utils.ResponseSuccess(w, map[string]bool{"success": true})
 }
 
-func (u *Users) checkPermission(r *http.Request, permission schema.Permission) bool {
-	userID := utils.Session.Get(r, "user_id")
-	if userID == nil {
-		return false
+// ResetTwoFactor removes a user's 2FA enrollment, e.g. after a lost device
+func (u *Users) ResetTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := getScopedUser(w, r, userID); !ok {
+		return
+	}
+
+	if err := utils.DB.DisableTwoFactor(userID); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+// Unlock clears the failed login counter and lockout of a user
+func (u *Users) Unlock(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if userID == "" {
+		utils.ResponseErrorStatus(w, nil, http.StatusBadRequest)
+		return
 	}
 
-	user, err := utils.DB.GetUser(userID.(string))
+	if _, ok := getScopedUser(w, r, userID); !ok {
+		return
+	}
+
+	if err := utils.DB.ResetLoginFailures(userID); err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	event := utils.NewAuditEvent(r, schema.AuditAccountUnlock)
+	event.UserID = userID
+	utils.DB.RecordAudit(event)
+
+	user, err := utils.DB.GetUser(userID)
 	if err != nil {
-		return false
+		utils.ResponseError(w, err)
+		return
 	}
 
-	return user.HasPermission(permission)
-}
\ No newline at end of file
+	utils.ResponseSuccess(w, user)
+}
+
+// getScopedUser loads a user the current user may manage. Users outside the
+// caller's tenant or with permissions the caller lacks are reported as not found.
+func getScopedUser(w http.ResponseWriter, r *http.Request, userID string) (*schema.User, bool) {
+	actor, err := utils.GetAuthUser(r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return nil, false
+	}
+
+	user, err := utils.DB.GetUser(userID)
+	if err != nil || !utils.DB.CanManageUser(actor, user) {
+		utils.ResponseErrorStatus(w, errors.New("user not found"), http.StatusNotFound)
+		return nil, false
+	}
+
+	return user, true
+}
@@ -0,0 +1,285 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/middleware"
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+const webAuthnChallengeTTL = 5 * time.Minute
+
+// webAuthnChallenge holds the state of a pending WebAuthn ceremony
+type webAuthnChallenge struct {
+	Session webauthn.SessionData
+	UserID  string
+}
+
+type WebAuthn struct{}
+
+// BeginRegistration returns the options for registering a new credential for the current user
+func (wa *WebAuthn) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	if !wa.checkSessionAuth(w, r) {
+		return
+	}
+
+	relyingParty, err := utils.WebAuthn.Get(r)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	user, err := utils.DB.GetWebAuthnUser(utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	// Prevent registering the same authenticator twice
+	exclude := webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()
+	options, session, err := relyingParty.BeginRegistration(user, webauthn.WithExclusions(exclude))
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.Cache.Set("webauthn:register:"+user.User.ID, &webAuthnChallenge{
+		Session: *session,
+		UserID:  user.User.ID,
+	}, webAuthnChallengeTTL)
+
+	utils.ResponseSuccess(w, schema.WebAuthnChallengeResponse{
+		Options:   options,
+		ExpiresAt: time.Now().Add(webAuthnChallengeTTL),
+	})
+}
+
+// FinishRegistration verifies the attestation from the browser and stores the credential.
+// The request body is the PublicKeyCredential returned by navigator.credentials.create().
+func (wa *WebAuthn) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	if !wa.checkSessionAuth(w, r) {
+		return
+	}
+
+	userID := utils.GetAuthUserID(r)
+	cacheKey := "webauthn:register:" + userID
+	challenge, ok := utils.Cache.Get(cacheKey).(*webAuthnChallenge)
+	if !ok {
+		utils.ResponseErrorStatus(w, errors.New("registration challenge expired"), http.StatusBadRequest)
+		return
+	}
+	utils.Cache.Delete(cacheKey)
+
+	relyingParty, err := utils.WebAuthn.Get(r)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	user, err := utils.DB.GetWebAuthnUser(userID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	credential, err := relyingParty.FinishRegistration(user, challenge.Session, r)
+	if err != nil {
+		utils.ResponseErrorStatus(w, fmt.Errorf("registration failed: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := utils.DB.AddWebAuthnCredential(userID, r.URL.Query().Get("name"), credential)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+		return
+	}
+
+	utils.ResponseSuccess(w, result)
+}
+
+// BeginLogin returns the options for a passkey login. When the username is
+// unknown or has no credentials, any discoverable credential is accepted
+// so the response does not reveal which accounts exist.
+func (wa *WebAuthn) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	var body schema.WebAuthnLoginBeginRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			utils.ResponseErrorStatus(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	relyingParty, err := utils.WebAuthn.Get(r)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	var options interface{}
+	challenge := &webAuthnChallenge{}
+
+	var user *utils.WebAuthnUser
+	if body.Username != "" {
+		if u, err := utils.DB.GetUserByUsername(body.Username); err == nil && u.Enabled {
+			if user, err = utils.DB.GetWebAuthnUser(u.ID); err != nil || len(user.Credentials) == 0 {
+				user = nil
+			}
+		}
+	}
+
+	if user != nil {
+		assertion, session, err := relyingParty.BeginLogin(user)
+		if err != nil {
+			utils.ResponseError(w, err)
+			return
+		}
+		options = assertion
+		challenge.Session = *session
+		challenge.UserID = user.User.ID
+	} else {
+		assertion, session, err := relyingParty.BeginDiscoverableLogin()
+		if err != nil {
+			utils.ResponseError(w, err)
+			return
+		}
+		options = assertion
+		challenge.Session = *session
+	}
+
+	challengeID, err := utils.GenerateToken()
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+	utils.Cache.Set("webauthn:login:"+challengeID, challenge, webAuthnChallengeTTL)
+
+	utils.ResponseSuccess(w, schema.WebAuthnChallengeResponse{
+		ChallengeID: challengeID,
+		Options:     options,
+		ExpiresAt:   time.Now().Add(webAuthnChallengeTTL),
+	})
+}
+
+// FinishLogin verifies the assertion from the browser and starts a session.
+// The request body is the PublicKeyCredential returned by navigator.credentials.get().
+func (wa *WebAuthn) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	auth := &Auth{}
+	if !auth.checkLoginAllowed(w, r, "") {
+		return
+	}
+
+	cacheKey := "webauthn:login:" + r.URL.Query().Get("challenge_id")
+	challenge, ok := utils.Cache.Get(cacheKey).(*webAuthnChallenge)
+	if !ok {
+		utils.ResponseErrorStatus(w, errors.New("login challenge expired"), http.StatusUnauthorized)
+		return
+	}
+	utils.Cache.Delete(cacheKey)
+
+	relyingParty, err := utils.WebAuthn.Get(r)
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	var user *utils.WebAuthnUser
+	var credential *webauthn.Credential
+	if challenge.UserID != "" {
+		user, err = utils.DB.GetWebAuthnUser(challenge.UserID)
+		if err == nil {
+			credential, err = relyingParty.FinishLogin(user, challenge.Session, r)
+		}
+	} else {
+		var discovered webauthn.User
+		discovered, credential, err = relyingParty.FinishPasskeyLogin(func(rawID, userHandle []byte) (webauthn.User, error) {
+			return utils.DB.GetWebAuthnUser(string(userHandle))
+		}, challenge.Session, r)
+		if err == nil {
+			user = discovered.(*utils.WebAuthnUser)
+		}
+	}
+
+	if err == nil {
+		err = utils.DB.UpdateWebAuthnLogin(credential)
+	}
+	if err == nil && !user.User.Enabled {
+		err = errors.New("user account is disabled")
+	}
+
+	if err != nil {
+		username := ""
+		if user != nil {
+			username = user.User.Username
+		}
+		utils.LoginGuard.RecordFailure(r, username, err)
+		if !errors.Is(err, utils.ErrWebAuthnCloned) {
+			err = errors.New("passkey verification failed")
+		}
+		utils.ResponseErrorStatus(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	// The account is only known once the credential resolved it
+	if !auth.checkLoginAllowed(w, r, user.User.Username) {
+		return
+	}
+
+	auth.startSession(w, r, user.User)
+}
+
+// GetCredentials lists the credentials registered by the current user
+func (wa *WebAuthn) GetCredentials(w http.ResponseWriter, r *http.Request) {
+	credentials, err := utils.DB.ListWebAuthnCredentials(utils.GetAuthUserID(r))
+	if err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, credentials)
+}
+
+// DeleteCredential removes a credential of the current user. Admins may remove any credential.
+func (wa *WebAuthn) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	if !wa.checkSessionAuth(w, r) {
+		return
+	}
+
+	credentialID := r.PathValue("id")
+	credential, err := utils.DB.GetWebAuthnCredential(credentialID)
+	if err != nil {
+		utils.ResponseErrorStatus(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Admins may only remove the passkeys of users they are allowed to manage
+	if credential.UserID != utils.GetAuthUserID(r) {
+		if !middleware.HasPermission(r, schema.PermissionWriteUsers) {
+			utils.ResponseErrorStatus(w, nil, http.StatusForbidden)
+			return
+		}
+		if _, ok := getScopedUser(w, r, credential.UserID); !ok {
+			return
+		}
+	}
+
+	if err := utils.DB.DeleteWebAuthnCredential(credentialID); err != nil {
+		utils.ResponseError(w, err)
+		return
+	}
+
+	utils.ResponseSuccess(w, map[string]bool{"success": true})
+}
+
+func (wa *WebAuthn) checkSessionAuth(w http.ResponseWriter, r *http.Request) bool {
+	if auth := utils.GetAuth(r); auth != nil && auth.Method == utils.AuthMethodToken {
+		utils.ResponseErrorStatus(w, errors.New("access tokens cannot manage passkeys"), http.StatusForbidden)
+		return false
+	}
+	return true
+}
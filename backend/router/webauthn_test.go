@@ -0,0 +1,71 @@
+package router
+
+import (
+	"khairul169/garage-webui/schema"
+	"khairul169/garage-webui/utils"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// addTestUsers stores users and tenants in the database for the duration of a test
+func addTestUsers(t *testing.T, tenants []string, users ...*schema.User) {
+	t.Setenv("DATA_DIR", t.TempDir())
+	for _, id := range tenants {
+		utils.DB.Tenants[id] = &schema.Tenant{ID: id, Name: id, Enabled: true}
+	}
+	for _, user := range users {
+		utils.DB.Users[user.ID] = user
+	}
+	t.Cleanup(func() {
+		for _, id := range tenants {
+			delete(utils.DB.Tenants, id)
+		}
+		for _, user := range users {
+			delete(utils.DB.Users, user.ID)
+		}
+	})
+}
+
+// withTestAuth authenticates a request as the user with a session
+func withTestAuth(r *http.Request, user *schema.User) *http.Request {
+	return utils.WithAuth(r, &utils.AuthInfo{UserID: user.ID, Method: utils.AuthMethodSession, User: user})
+}
+
+func TestDeleteCredentialOfAnotherTenant(t *testing.T) {
+	acme, globex := "acme", "globex"
+	tenantAdmin := &schema.User{ID: "passkey-tenant-admin", Role: schema.RoleTenantAdmin, TenantID: &acme, Enabled: true}
+	member := &schema.User{ID: "passkey-member", Role: schema.RoleUser, TenantID: &acme, Enabled: true}
+	outsider := &schema.User{ID: "passkey-outsider", Role: schema.RoleUser, TenantID: &globex, Enabled: true}
+	admin := &schema.User{ID: "passkey-admin", Role: schema.RoleAdmin, Enabled: true}
+	addTestUsers(t, []string{acme, globex}, tenantAdmin, member, outsider, admin)
+
+	tests := []struct {
+		owner *schema.User
+		want  int
+	}{
+		{outsider, http.StatusNotFound},
+		{admin, http.StatusNotFound},
+		{member, http.StatusOK},
+	}
+
+	webAuthn := &WebAuthn{}
+	for _, tt := range tests {
+		credentialID := "credential-" + tt.owner.ID
+		utils.DB.WebAuthnCredentials[credentialID] = &schema.WebAuthnCredential{ID: credentialID, UserID: tt.owner.ID}
+		defer delete(utils.DB.WebAuthnCredentials, credentialID)
+
+		r := httptest.NewRequest(http.MethodDelete, "/auth/webauthn/credentials/"+credentialID, nil)
+		r.SetPathValue("id", credentialID)
+		w := httptest.NewRecorder()
+		webAuthn.DeleteCredential(w, withTestAuth(r, tenantAdmin))
+
+		if w.Code != tt.want {
+			t.Errorf("deleting the passkey of %s: status = %d, want %d", tt.owner.ID, w.Code, tt.want)
+		}
+		_, exists := utils.DB.WebAuthnCredentials[credentialID]
+		if exists != (tt.want != http.StatusOK) {
+			t.Errorf("passkey of %s exists = %v after status %d", tt.owner.ID, exists, w.Code)
+		}
+	}
+}
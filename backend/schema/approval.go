@@ -0,0 +1,49 @@
+package schema
+
+import "time"
+
+// ApprovalOperationDeleteDirectory is the recursive directory delete of the object browser.
+// The other operations that can require approval are named after their Garage admin endpoint.
+const ApprovalOperationDeleteDirectory = "DeleteDirectory"
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending   ApprovalStatus = "pending"
+	ApprovalApproved  ApprovalStatus = "approved"
+	ApprovalExecuted  ApprovalStatus = "executed"
+	ApprovalFailed    ApprovalStatus = "failed"
+	ApprovalDenied    ApprovalStatus = "denied"
+	ApprovalCancelled ApprovalStatus = "cancelled"
+	ApprovalExpired   ApprovalStatus = "expired"
+)
+
+// ApprovalRequest is a destructive operation held back until a second admin approves it.
+// Requests are kept after they are decided as the history of the queue.
+type ApprovalRequest struct {
+	ID          string          `json:"id"`
+	Operation   string          `json:"operation"`
+	Permission  Permission      `json:"permission,omitempty"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Query       string          `json:"query,omitempty"`
+	Body        string          `json:"body,omitempty"`
+	Summary     string          `json:"summary"`
+	RequestedBy string          `json:"requested_by"`
+	Status      ApprovalStatus  `json:"status"`
+	ReviewedBy  string          `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time      `json:"reviewed_at,omitempty"`
+	Result      string          `json:"result,omitempty"`
+	History     []ApprovalEvent `json:"history"`
+	ExpiresAt   time.Time       `json:"expires_at"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ApprovalEvent records a status change of an approval request
+type ApprovalEvent struct {
+	Status  ApprovalStatus `json:"status"`
+	UserID  string         `json:"user_id,omitempty"`
+	Message string         `json:"message,omitempty"`
+	At      time.Time      `json:"at"`
+}
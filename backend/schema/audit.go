@@ -0,0 +1,61 @@
+package schema
+
+import "time"
+
+type AuditEventType string
+
+const (
+	AuditLoginSucceeded         AuditEventType = "login_succeeded"
+	AuditLoginFailed            AuditEventType = "login_failed"
+	AuditLoginBlocked           AuditEventType = "login_blocked"
+	AuditAccountLocked          AuditEventType = "account_locked"
+	AuditAccountUnlock          AuditEventType = "account_unlocked"
+	AuditPasswordResetRequested AuditEventType = "password_reset_requested"
+	AuditPasswordReset          AuditEventType = "password_reset"
+	AuditUserInvited            AuditEventType = "user_invited"
+	AuditInviteAccepted         AuditEventType = "invite_accepted"
+	AuditElevationRequested     AuditEventType = "elevation_requested"
+	AuditElevationGranted       AuditEventType = "elevation_granted"
+	AuditElevationDenied        AuditEventType = "elevation_denied"
+	AuditElevationRevoked       AuditEventType = "elevation_revoked"
+	AuditElevationExpired       AuditEventType = "elevation_expired"
+	AuditImpersonationStarted   AuditEventType = "impersonation_started"
+	AuditImpersonationEnded     AuditEventType = "impersonation_ended"
+	AuditRoleCreated            AuditEventType = "role_created"
+	AuditRoleUpdated            AuditEventType = "role_updated"
+	AuditRoleDeleted            AuditEventType = "role_deleted"
+	AuditGroupCreated           AuditEventType = "group_created"
+	AuditGroupUpdated           AuditEventType = "group_updated"
+	AuditGroupDeleted           AuditEventType = "group_deleted"
+	AuditTenantAssigned         AuditEventType = "tenant_assigned"
+	AuditBucketGrantSet         AuditEventType = "bucket_grant_set"
+	AuditBucketGrantDeleted     AuditEventType = "bucket_grant_deleted"
+	AuditApprovalRequested      AuditEventType = "approval_requested"
+	AuditApprovalGranted        AuditEventType = "approval_granted"
+	AuditApprovalDenied         AuditEventType = "approval_denied"
+	AuditApprovalCancelled      AuditEventType = "approval_cancelled"
+	AuditApprovalExpired        AuditEventType = "approval_expired"
+)
+
+// AuditEvent records a security relevant event
+type AuditEvent struct {
+	ID             string         `json:"id"`
+	Type           AuditEventType `json:"type"`
+	UserID         string         `json:"user_id,omitempty"`
+	Username       string         `json:"username,omitempty"`
+	ActorID        string         `json:"actor_id,omitempty"`
+	ImpersonatorID string         `json:"impersonator_id,omitempty"`
+	IP             string         `json:"ip,omitempty"`
+	UserAgent      string         `json:"user_agent,omitempty"`
+	Message        string         `json:"message,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// AuditQuery filters audit events
+type AuditQuery struct {
+	Type     AuditEventType
+	UserID   string
+	Username string
+	IP       string
+	Limit    int
+}
@@ -0,0 +1,32 @@
+package schema
+
+// Object actions of the explain endpoint, decided by bucket grants like the object browser
+const (
+	AuthzActionReadObjects  = "read_objects"
+	AuthzActionWriteObjects = "write_objects"
+)
+
+// AuthzExplanation reports whether a user may perform an action on a resource and why
+type AuthzExplanation struct {
+	UserID       string                `json:"user_id"`
+	Username     string                `json:"username"`
+	Action       string                `json:"action"`
+	Resource     string                `json:"resource,omitempty"`
+	Allowed      bool                  `json:"allowed"`
+	Reason       string                `json:"reason"`
+	Role         Role                  `json:"role"`
+	ElevatedRole *Role                 `json:"elevated_role,omitempty"`
+	Groups       []AuthzGroup          `json:"groups"`
+	TenantID     *string               `json:"tenant_id"`
+	TenantScoped bool                  `json:"tenant_scoped"`
+	GrantedBy    []string              `json:"granted_by"`
+	BucketAccess *BucketAccessDecision `json:"bucket_access,omitempty"`
+}
+
+// AuthzGroup is a group membership contributing roles or a tenant to a user
+type AuthzGroup struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Roles    []Role  `json:"roles"`
+	TenantID *string `json:"tenant_id"`
+}
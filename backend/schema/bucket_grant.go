@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"strings"
+	"time"
+)
+
+// BucketAccess is the level of access a grant gives to the objects of a bucket
+type BucketAccess string
+
+const (
+	BucketAccessNone  BucketAccess = "none"
+	BucketAccessRead  BucketAccess = "read"
+	BucketAccessWrite BucketAccess = "write"
+)
+
+// IsValid checks if the access level is one of the known levels
+func (a BucketAccess) IsValid() bool {
+	return a == BucketAccessNone || a == BucketAccessRead || a == BucketAccessWrite
+}
+
+// Allows checks if the access level includes another one
+func (a BucketAccess) Allows(required BucketAccess) bool {
+	return a.rank() >= required.rank()
+}
+
+func (a BucketAccess) rank() int {
+	switch a {
+	case BucketAccessRead:
+		return 1
+	case BucketAccessWrite:
+		return 2
+	}
+	return 0
+}
+
+// BucketGrant gives a user or the members of a group access to the objects of a
+// bucket in the object browser, optionally limited to keys under a prefix
+type BucketGrant struct {
+	ID        string       `json:"id"`
+	BucketID  string       `json:"bucket_id"`
+	UserID    *string      `json:"user_id,omitempty"`
+	GroupID   *string      `json:"group_id,omitempty"`
+	Access    BucketAccess `json:"access"`
+	Prefix    string       `json:"prefix"`
+	CreatedBy string       `json:"created_by"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// Matches checks if the grant covers an object key or listing prefix
+func (g *BucketGrant) Matches(key string) bool {
+	return strings.HasPrefix(key, g.Prefix)
+}
+
+// CreateBucketGrantRequest grants a user or group access to a bucket. A grant for the
+// same subject and prefix replaces the existing one.
+type CreateBucketGrantRequest struct {
+	UserID  *string      `json:"user_id,omitempty"`
+	GroupID *string      `json:"group_id,omitempty"`
+	Access  BucketAccess `json:"access"`
+	Prefix  string       `json:"prefix"`
+}
+
+// BucketAccessDecision explains the access a user has to a key of a bucket
+type BucketAccessDecision struct {
+	Access BucketAccess `json:"access"`
+	Reason string       `json:"reason"`
+	Grant  *BucketGrant `json:"grant,omitempty"`
+}
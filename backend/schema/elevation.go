@@ -0,0 +1,47 @@
+package schema
+
+import "time"
+
+type ElevationStatus string
+
+const (
+	ElevationPending  ElevationStatus = "pending"
+	ElevationApproved ElevationStatus = "approved"
+	ElevationDenied   ElevationStatus = "denied"
+	ElevationRevoked  ElevationStatus = "revoked"
+	ElevationExpired  ElevationStatus = "expired"
+)
+
+// ElevationRequest is a user's request for a temporary, admin approved role elevation
+type ElevationRequest struct {
+	ID         string          `json:"id"`
+	UserID     string          `json:"user_id"`
+	Role       Role            `json:"role"`
+	Reason     string          `json:"reason"`
+	Duration   string          `json:"duration"`
+	Status     ElevationStatus `json:"status"`
+	ReviewedBy string          `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time      `json:"reviewed_at,omitempty"`
+	ExpiresAt  *time.Time      `json:"expires_at,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// Elevation is an approved role elevation that is active on a user until it expires
+type Elevation struct {
+	RequestID string    `json:"request_id"`
+	Role      Role      `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IsActive reports whether the elevation has not expired yet
+func (e *Elevation) IsActive() bool {
+	return e != nil && time.Now().Before(e.ExpiresAt)
+}
+
+// CreateElevationRequest represents the request to temporarily elevate the current user's role
+type CreateElevationRequest struct {
+	Role     Role   `json:"role"`
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
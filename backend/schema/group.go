@@ -0,0 +1,47 @@
+package schema
+
+import "time"
+
+// Group is a named set of users that inherit the group's roles and tenant.
+// Groups are managed by admins or provisioned by an identity provider through SCIM.
+type Group struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	ExternalID string    `json:"external_id,omitempty"`
+	Members    []string  `json:"members"`
+	Roles      []Role    `json:"roles"`
+	TenantID   *string   `json:"tenant_id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// HasMember checks if a user belongs to the group
+func (g *Group) HasMember(userID string) bool {
+	for _, member := range g.Members {
+		if member == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateGroupRequest represents the request to create a group
+type CreateGroupRequest struct {
+	Name     string   `json:"name"`
+	Roles    []Role   `json:"roles"`
+	TenantID *string  `json:"tenant_id"`
+	Members  []string `json:"members"`
+}
+
+// UpdateGroupRequest represents the request to update a group
+type UpdateGroupRequest struct {
+	Name     *string  `json:"name,omitempty"`
+	Roles    []Role   `json:"roles,omitempty"`
+	TenantID *string  `json:"tenant_id,omitempty"`
+	Members  []string `json:"members,omitempty"`
+}
+
+// GroupMembersRequest lists users to add to a group
+type GroupMembersRequest struct {
+	UserIDs []string `json:"user_ids"`
+}
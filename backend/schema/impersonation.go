@@ -0,0 +1,28 @@
+package schema
+
+import "time"
+
+// Impersonation lets an admin's session act as another user, read-only and until it expires
+type Impersonation struct {
+	UserID    string    `json:"user_id"`
+	Reason    string    `json:"reason"`
+	StartedAt time.Time `json:"started_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IsActive reports whether the impersonation has not expired yet
+func (i *Impersonation) IsActive() bool {
+	return i != nil && time.Now().Before(i.ExpiresAt)
+}
+
+// StartImpersonationRequest represents an admin's request to view the web UI as another user
+type StartImpersonationRequest struct {
+	Reason   string `json:"reason"`
+	Duration string `json:"duration"`
+}
+
+// ImpersonationStatus tells the client that the session is impersonating a user, and by whom
+type ImpersonationStatus struct {
+	Impersonator User      `json:"impersonator"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,44 @@
+package schema
+
+import "time"
+
+// Invitation is a single-use token letting an invited user activate their pending account
+type Invitation struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	InvitedBy string    `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InviteUserRequest represents the request to invite a new user
+type InviteUserRequest struct {
+	Email    string  `json:"email"`
+	Role     Role    `json:"role"`
+	TenantID *string `json:"tenant_id"`
+}
+
+// InviteUserResponse contains the invite token, which is only shown once and only
+// when the invitation was not emailed to the invitee
+type InviteUserResponse struct {
+	User      User      `json:"user"`
+	Token     string    `json:"token,omitempty"`
+	InviteURL string    `json:"invite_url,omitempty"`
+	EmailSent bool      `json:"email_sent"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// InvitationInfo describes a pending invitation to the invitee
+type InvitationInfo struct {
+	Email     string    `json:"email"`
+	Role      Role      `json:"role"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AcceptInviteRequest activates an invited account with the invitee's chosen credentials
+type AcceptInviteRequest struct {
+	Token    string `json:"token"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
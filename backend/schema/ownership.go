@@ -0,0 +1,16 @@
+package schema
+
+import "time"
+
+// ResourceOwner records the tenant a Garage bucket or access key belongs to.
+// Resources without an owner are shared by every user without a tenant.
+type ResourceOwner struct {
+	TenantID   string    `json:"tenant_id"`
+	AssignedBy string    `json:"assigned_by"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// AssignTenantRequest moves a bucket or access key to a tenant, or back to the shared pool when empty
+type AssignTenantRequest struct {
+	TenantID *string `json:"tenant_id"`
+}
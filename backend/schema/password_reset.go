@@ -0,0 +1,30 @@
+package schema
+
+import "time"
+
+// PasswordReset is a single-use token allowing a user to set a new password
+type PasswordReset struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ForgotPasswordRequest starts the password reset flow for a username or email address
+type ForgotPasswordRequest struct {
+	Username string `json:"username"`
+}
+
+// ResetPasswordRequest consumes a reset token to set a new password
+type ResetPasswordRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+// PasswordResetResponse contains a reset token generated by an admin, which is only shown once
+type PasswordResetResponse struct {
+	Token     string    `json:"token"`
+	ResetURL  string    `json:"reset_url,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,39 @@
+package schema
+
+import "time"
+
+// BuiltinRoles lists the roles shipped with the web UI, which cannot be edited
+var BuiltinRoles = []Role{RoleAdmin, RoleTenantAdmin, RoleUser, RoleReadOnly}
+
+// IsBuiltin checks if the role is one of the built-in roles
+func (r Role) IsBuiltin() bool {
+	for _, builtin := range BuiltinRoles {
+		if r == builtin {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleDefinition is a named set of permissions that can be assigned to users
+type RoleDefinition struct {
+	Name        Role         `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	Builtin     bool         `json:"builtin"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// CreateRoleRequest represents the request to create a custom role
+type CreateRoleRequest struct {
+	Name        Role         `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+}
+
+// UpdateRoleRequest represents the request to update a custom role
+type UpdateRoleRequest struct {
+	Description *string      `json:"description,omitempty"`
+	Permissions []Permission `json:"permissions,omitempty"`
+}
@@ -0,0 +1,38 @@
+package schema
+
+import "time"
+
+// AccessTokenPrefix marks personal access tokens so they can be told apart from session tokens
+const AccessTokenPrefix = "gwui_"
+
+// AccessToken is a long-lived personal access token limited to a set of permissions
+type AccessToken struct {
+	ID         string       `json:"id"`
+	UserID     string       `json:"user_id"`
+	Name       string       `json:"name"`
+	TokenHash  string       `json:"token_hash,omitempty"`
+	Hint       string       `json:"hint"`
+	Scopes     []Permission `json:"scopes"`
+	ExpiresAt  *time.Time   `json:"expires_at"`
+	LastUsedAt *time.Time   `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// CreateAccessTokenRequest represents the request to create a personal access token
+type CreateAccessTokenRequest struct {
+	Name      string       `json:"name"`
+	UserID    *string      `json:"user_id,omitempty"`
+	Scopes    []Permission `json:"scopes"`
+	ExpiresAt *time.Time   `json:"expires_at,omitempty"`
+}
+
+// CreateAccessTokenResponse contains the token secret, which is only shown once
+type CreateAccessTokenResponse struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+// IsExpired reports whether the token has passed its expiry time
+func (t *AccessToken) IsExpired() bool {
+	return t.ExpiresAt != nil && time.Now().After(*t.ExpiresAt)
+}
@@ -0,0 +1,77 @@
+package schema
+
+import "time"
+
+// TwoFactor holds a user's TOTP enrollment. It is stored separately from the
+// user record so the secret is never included in user API responses.
+type TwoFactor struct {
+	UserID        string     `json:"user_id"`
+	Secret        string     `json:"secret"`
+	PendingSecret string     `json:"pending_secret,omitempty"`
+	Enabled       bool       `json:"enabled"`
+	RecoveryCodes []string   `json:"recovery_codes"`
+	LastUsedStep  int64      `json:"last_used_step"`
+	EnabledAt     *time.Time `json:"enabled_at"`
+}
+
+// SecuritySettings holds security options managed by admins at runtime
+type SecuritySettings struct {
+	RequireTwoFactorRoles []Role `json:"require_two_factor_roles"`
+	// ApprovalOperations lists the destructive operations a second admin must approve
+	ApprovalOperations []string `json:"approval_operations"`
+}
+
+// RequiresTwoFactor checks if users with the given role must enroll in 2FA
+func (s *SecuritySettings) RequiresTwoFactor(role Role) bool {
+	for _, r := range s.RequireTwoFactorRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresApproval checks if an operation is held for approval by a second admin
+func (s *SecuritySettings) RequiresApproval(operation string) bool {
+	for _, op := range s.ApprovalOperations {
+		if op == operation {
+			return true
+		}
+	}
+	return false
+}
+
+// TwoFactorSetupResponse contains the secret to add to an authenticator app
+type TwoFactorSetupResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorCodeRequest represents a request confirmed with a TOTP code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorDisableRequest represents the request to disable 2FA
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// RecoveryCodesResponse contains one-time recovery codes, which are only shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorChallengeResponse is returned by login when a second factor is needed
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	TwoFactorToken    string    `json:"two_factor_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest represents the second login step
+type TwoFactorLoginRequest struct {
+	TwoFactorToken string `json:"two_factor_token"`
+	Code           string `json:"code"`
+}
@@ -13,6 +13,14 @@ const (
 	RoleTenantAdmin Role = "tenant_admin"
 )
 
+// Authentication sources for user accounts
+const (
+	AuthSourceLocal = "local"
+	AuthSourceOIDC  = "oidc"
+	AuthSourceLDAP  = "ldap"
+	AuthSourceProxy = "proxy"
+)
+
 type Permission string
 
 const (
@@ -33,6 +41,26 @@ const (
 	PermissionSystemAdmin      Permission = "system_admin"
 )
 
+// AllPermissions lists every permission known to the web UI
+var AllPermissions = []Permission{
+	PermissionReadBuckets, PermissionWriteBuckets, PermissionDeleteBuckets,
+	PermissionReadKeys, PermissionWriteKeys, PermissionDeleteKeys,
+	PermissionReadCluster, PermissionWriteCluster,
+	PermissionReadUsers, PermissionWriteUsers, PermissionDeleteUsers,
+	PermissionReadTenants, PermissionWriteTenants, PermissionDeleteTenants,
+	PermissionSystemAdmin,
+}
+
+// IsValid checks if the permission is one of the known permissions
+func (p Permission) IsValid() bool {
+	for _, known := range AllPermissions {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
 type User struct {
 	ID          string    `json:"id"`
 	Username    string    `json:"username"`
@@ -41,6 +69,18 @@ type User struct {
 	Role        Role      `json:"role"`
 	TenantID    *string   `json:"tenant_id"`
 	Enabled     bool      `json:"enabled"`
+	TwoFactorEnabled bool `json:"two_factor_enabled"`
+	AuthSource  string    `json:"auth_source,omitempty"`
+	ExternalID  string    `json:"external_id,omitempty"`
+	SCIMProvisioned bool  `json:"scim_provisioned,omitempty"`
+	SCIMExternalID  string `json:"scim_external_id,omitempty"`
+	FailedLoginAttempts int        `json:"failed_login_attempts"`
+	LastFailedLogin     *time.Time `json:"last_failed_login"`
+	LockedUntil         *time.Time `json:"locked_until"`
+	MustChangePassword  bool       `json:"must_change_password"`
+	InvitePending       bool       `json:"invite_pending,omitempty"`
+	PasswordChangedAt   *time.Time `json:"password_changed_at"`
+	Elevation           *Elevation `json:"elevation,omitempty"`
 	LastLogin   *time.Time `json:"last_login"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
@@ -59,11 +99,22 @@ type Tenant struct {
 }
 
 type Session struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	Token     string    `json:"-"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	Token          string    `json:"-"`
+	TokenHash      string    `json:"token_hash,omitempty"`
+	IPAddress      string    `json:"ip_address"`
+	UserAgent      string    `json:"user_agent"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	Impersonation  *Impersonation `json:"impersonation,omitempty"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// SessionResponse is a session as listed to users, marking the one the request was made with
+type SessionResponse struct {
+	Session
+	Current bool `json:"current,omitempty"`
 }
 
 // CreateUserRequest represents the request to create a new user
@@ -74,6 +125,7 @@ type CreateUserRequest struct {
 	Role     Role    `json:"role"`
 	TenantID *string `json:"tenant_id"`
 	Enabled  bool    `json:"enabled"`
+	MustChangePassword bool `json:"must_change_password"`
 }
 
 // UpdateUserRequest represents the request to update a user
@@ -84,6 +136,7 @@ type UpdateUserRequest struct {
 	Role     *Role   `json:"role,omitempty"`
 	TenantID *string `json:"tenant_id,omitempty"`
 	Enabled  *bool   `json:"enabled,omitempty"`
+	MustChangePassword *bool `json:"must_change_password,omitempty"`
 }
 
 // CreateTenantRequest represents the request to create a new tenant
@@ -110,12 +163,15 @@ type UpdateTenantRequest struct {
 type LoginRequest struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Code is an optional TOTP or recovery code to log in with 2FA in a single step
+	Code string `json:"code,omitempty"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
 	User         User   `json:"user"`
 	Token        string `json:"token"`
+	CSRFToken    string `json:"csrf_token"`
 	ExpiresAt    time.Time `json:"expires_at"`
 }
 
@@ -124,9 +180,33 @@ type AuthStatusResponse struct {
 	Enabled       bool  `json:"enabled"`
 	Authenticated bool  `json:"authenticated"`
 	User          *User `json:"user,omitempty"`
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required"`
+	PasswordChangeRequired bool `json:"password_change_required"`
+	CSRFToken string `json:"csrf_token,omitempty"`
+	Impersonation *ImpersonationStatus `json:"impersonation,omitempty"`
+}
+
+// ChangePasswordRequest represents a user changing their own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// AuthProvider describes an external login method
+type AuthProvider struct {
+	Enabled  bool   `json:"enabled"`
+	Name     string `json:"name,omitempty"`
+	LoginURL string `json:"login_url,omitempty"`
+	LogoutURL string `json:"logout_url,omitempty"`
+}
+
+// AuthProvidersResponse lists the external login methods
+type AuthProvidersResponse struct {
+	OIDC AuthProvider `json:"oidc"`
+	ForwardAuth AuthProvider `json:"forward_auth"`
 }
 
-// GetRolePermissions returns the permissions for a given role
+// GetRolePermissions returns the permissions of a built-in role. Custom roles are resolved by the database.
 func GetRolePermissions(role Role) []Permission {
 	switch role {
 	case RoleAdmin:
@@ -160,13 +240,7 @@ func GetRolePermissions(role Role) []Permission {
 	}
 }
 
-// HasPermission checks if a user has a specific permission
-func (u *User) HasPermission(permission Permission) bool {
-	permissions := GetRolePermissions(u.Role)
-	for _, p := range permissions {
-		if p == permission {
-			return true
-		}
-	}
-	return false
-}
\ No newline at end of file
+// IsLocked reports whether the account is temporarily locked after failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
@@ -0,0 +1,33 @@
+package schema
+
+import "time"
+
+// WebAuthnCredential is a FIDO2/WebAuthn public key credential (passkey or security key) registered by a user
+type WebAuthnCredential struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"user_id"`
+	Name            string     `json:"name"`
+	CredentialID    []byte     `json:"credential_id"`
+	PublicKey       []byte     `json:"public_key,omitempty"`
+	AttestationType string     `json:"attestation_type"`
+	Transports      []string   `json:"transports"`
+	AAGUID          []byte     `json:"aaguid"`
+	SignCount       uint32     `json:"sign_count"`
+	BackupEligible  bool       `json:"backup_eligible"`
+	BackupState     bool       `json:"backup_state"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// WebAuthnLoginBeginRequest starts a passkey login. Without a username the
+// browser offers any discoverable credential registered for this site.
+type WebAuthnLoginBeginRequest struct {
+	Username string `json:"username"`
+}
+
+// WebAuthnChallengeResponse contains the options passed to the browser's WebAuthn API
+type WebAuthnChallengeResponse struct {
+	ChallengeID string      `json:"challenge_id,omitempty"`
+	Options     interface{} `json:"options"`
+	ExpiresAt   time.Time   `json:"expires_at"`
+}
@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"sort"
+	"strings"
+	"time"
+)
+
+// accessTokenTouchInterval limits how often last-used timestamps are written to disk
+const accessTokenTouchInterval = time.Minute
+
+// Access token operations
+func (db *Database) CreateAccessToken(userID string, req *schema.CreateAccessTokenRequest) (*schema.CreateAccessTokenResponse, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.Users[userID]; !exists {
+		return nil, errors.New("user not found")
+	}
+
+	secret, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	token := schema.AccessTokenPrefix + secret
+
+	accessToken := &schema.AccessToken{
+		ID:        GenerateID(),
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: HashToken(token),
+		Hint:      token[:len(schema.AccessTokenPrefix)+4] + "..." + token[len(token)-4:],
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	db.AccessTokens[accessToken.ID] = accessToken
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return &schema.CreateAccessTokenResponse{
+		AccessToken: sanitizeAccessToken(accessToken),
+		Token:       token,
+	}, nil
+}
+
+func (db *Database) GetAccessToken(id string) (*schema.AccessToken, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	accessToken, exists := db.AccessTokens[id]
+	if !exists {
+		return nil, errors.New("access token not found")
+	}
+
+	return accessToken, nil
+}
+
+// GetAccessTokenBySecret looks up a valid, unexpired access token by its secret
+func (db *Database) GetAccessTokenBySecret(token string) (*schema.AccessToken, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if !strings.HasPrefix(token, schema.AccessTokenPrefix) {
+		return nil, errors.New("access token not found")
+	}
+
+	tokenHash := HashToken(token)
+	for _, accessToken := range db.AccessTokens {
+		if accessToken.TokenHash == tokenHash {
+			if accessToken.IsExpired() {
+				return nil, errors.New("access token expired")
+			}
+			return accessToken, nil
+		}
+	}
+
+	return nil, errors.New("access token not found")
+}
+
+// ListAccessTokens returns the tokens owned by a user, newest first
+func (db *Database) ListAccessTokens(userID string) ([]schema.AccessToken, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tokens := make([]schema.AccessToken, 0)
+	for _, accessToken := range db.AccessTokens {
+		if accessToken.UserID == userID {
+			tokens = append(tokens, sanitizeAccessToken(accessToken))
+		}
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+
+	return tokens, nil
+}
+
+// TouchAccessToken records that a token has been used
+func (db *Database) TouchAccessToken(id string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	accessToken, exists := db.AccessTokens[id]
+	if !exists {
+		return errors.New("access token not found")
+	}
+
+	now := time.Now()
+	if accessToken.LastUsedAt != nil && now.Sub(*accessToken.LastUsedAt) < accessTokenTouchInterval {
+		return nil
+	}
+
+	accessToken.LastUsedAt = &now
+	return db.saveUnsafe()
+}
+
+func (db *Database) DeleteAccessToken(id string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.AccessTokens[id]; !exists {
+		return errors.New("access token not found")
+	}
+
+	delete(db.AccessTokens, id)
+	return db.saveUnsafe()
+}
+
+// sanitizeAccessToken returns a copy of the token without its hash
+func sanitizeAccessToken(accessToken *schema.AccessToken) schema.AccessToken {
+	result := *accessToken
+	result.TokenHash = ""
+	return result
+}
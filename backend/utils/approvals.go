@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"log"
+	"sort"
+	"time"
+)
+
+const approvalExpiryInterval = time.Minute
+
+// Approval operations
+
+// RequiresApproval checks if an operation is configured to wait for a second admin
+func (db *Database) RequiresApproval(operation string) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.Settings.RequiresApproval(operation)
+}
+
+// RequestApproval queues an operation until a second admin approves it. Pending
+// requests expire after APPROVAL_EXPIRY.
+func (db *Database) RequestApproval(approval *schema.ApprovalRequest) (*schema.ApprovalRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.Users[approval.RequestedBy]; !exists {
+		return nil, errors.New("user not found")
+	}
+
+	now := time.Now()
+	created := *approval
+	created.ID = GenerateID()
+	created.Status = schema.ApprovalPending
+	created.ExpiresAt = now.Add(getLoginDuration("APPROVAL_EXPIRY", 24*time.Hour))
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	created.History = nil
+	addApprovalEvent(&created, schema.ApprovalPending, approval.RequestedBy, created.Summary)
+	db.Approvals[created.ID] = &created
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := created
+	return &result, nil
+}
+
+func (db *Database) GetApproval(id string) (*schema.ApprovalRequest, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	approval, exists := db.Approvals[id]
+	if !exists {
+		return nil, errors.New("approval request not found")
+	}
+
+	result := *approval
+	return &result, nil
+}
+
+// ListApprovals returns approval requests, newest first. An empty userID returns the requests of all users.
+func (db *Database) ListApprovals(userID string, status schema.ApprovalStatus) []schema.ApprovalRequest {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	approvals := make([]schema.ApprovalRequest, 0)
+	for _, approval := range db.Approvals {
+		if userID != "" && approval.RequestedBy != userID {
+			continue
+		}
+		if status != "" && approval.Status != status {
+			continue
+		}
+		approvals = append(approvals, *approval)
+	}
+
+	sort.Slice(approvals, func(i, j int) bool {
+		return approvals[i].CreatedAt.After(approvals[j].CreatedAt)
+	})
+
+	return approvals
+}
+
+// GrantApproval approves a pending request. The caller executes the operation and
+// records its outcome with CompleteApproval.
+func (db *Database) GrantApproval(id, reviewerID string) (*schema.ApprovalRequest, error) {
+	return db.reviewApproval(id, reviewerID, schema.ApprovalApproved)
+}
+
+// DenyApproval rejects a pending request
+func (db *Database) DenyApproval(id, reviewerID string) (*schema.ApprovalRequest, error) {
+	return db.reviewApproval(id, reviewerID, schema.ApprovalDenied)
+}
+
+// CancelApproval withdraws a pending request
+func (db *Database) CancelApproval(id, userID string) (*schema.ApprovalRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	approval, err := db.getPendingApprovalUnsafe(id)
+	if err != nil {
+		return nil, err
+	}
+
+	approval.UpdatedAt = time.Now()
+	addApprovalEvent(approval, schema.ApprovalCancelled, userID, "")
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *approval
+	return &result, nil
+}
+
+// CompleteApproval records the outcome of executing an approved request
+func (db *Database) CompleteApproval(id string, result string, execErr error) (*schema.ApprovalRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	approval, exists := db.Approvals[id]
+	if !exists {
+		return nil, errors.New("approval request not found")
+	}
+	if approval.Status != schema.ApprovalApproved {
+		return nil, fmt.Errorf("approval request is %s", approval.Status)
+	}
+
+	status := schema.ApprovalExecuted
+	if execErr != nil {
+		status = schema.ApprovalFailed
+		result = execErr.Error()
+	}
+	approval.Result = result
+	approval.UpdatedAt = time.Now()
+	addApprovalEvent(approval, status, "", result)
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	completed := *approval
+	return &completed, nil
+}
+
+// ExpireApprovals marks pending requests past their expiry and returns them
+func (db *Database) ExpireApprovals() ([]schema.ApprovalRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	expired := make([]schema.ApprovalRequest, 0)
+	for _, approval := range db.Approvals {
+		if db.expireApprovalUnsafe(approval) {
+			expired = append(expired, *approval)
+		}
+	}
+
+	if len(expired) == 0 {
+		return expired, nil
+	}
+
+	return expired, db.saveUnsafe()
+}
+
+// StartApprovalExpiry periodically expires pending requests and records their expiry in the audit log
+func (db *Database) StartApprovalExpiry() {
+	ticker := time.NewTicker(approvalExpiryInterval)
+	go func() {
+		for range ticker.C {
+			expired, err := db.ExpireApprovals()
+			if err != nil {
+				log.Printf("Failed to expire approval requests: %v", err)
+			}
+
+			for _, approval := range expired {
+				db.RecordAudit(&schema.AuditEvent{
+					Type:    schema.AuditApprovalExpired,
+					UserID:  approval.RequestedBy,
+					Message: approval.Summary,
+				})
+			}
+		}
+	}()
+}
+
+func (db *Database) reviewApproval(id, reviewerID string, status schema.ApprovalStatus) (*schema.ApprovalRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	approval, err := db.getPendingApprovalUnsafe(id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.RequestedBy == reviewerID {
+		return nil, errors.New("cannot review your own request")
+	}
+
+	now := time.Now()
+	approval.ReviewedBy = reviewerID
+	approval.ReviewedAt = &now
+	approval.UpdatedAt = now
+	addApprovalEvent(approval, status, reviewerID, "")
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *approval
+	return &result, nil
+}
+
+func (db *Database) getPendingApprovalUnsafe(id string) (*schema.ApprovalRequest, error) {
+	approval, exists := db.Approvals[id]
+	if !exists {
+		return nil, errors.New("approval request not found")
+	}
+	if db.expireApprovalUnsafe(approval) {
+		if err := db.saveUnsafe(); err != nil {
+			return nil, err
+		}
+	}
+	if approval.Status != schema.ApprovalPending {
+		return nil, fmt.Errorf("approval request is already %s", approval.Status)
+	}
+	return approval, nil
+}
+
+func (db *Database) expireApprovalUnsafe(approval *schema.ApprovalRequest) bool {
+	if approval.Status != schema.ApprovalPending || time.Now().Before(approval.ExpiresAt) {
+		return false
+	}
+
+	approval.UpdatedAt = time.Now()
+	addApprovalEvent(approval, schema.ApprovalExpired, "", "")
+	return true
+}
+
+// addApprovalEvent moves a request to a status and appends the change to its history
+func addApprovalEvent(approval *schema.ApprovalRequest, status schema.ApprovalStatus, userID, message string) {
+	approval.Status = status
+	approval.History = append(approval.History, schema.ApprovalEvent{
+		Status:  status,
+		UserID:  userID,
+		Message: message,
+		At:      time.Now(),
+	})
+}
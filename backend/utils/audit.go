@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"khairul169/garage-webui/schema"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// NewAuditEvent creates an audit event with the client details of the request
+func NewAuditEvent(r *http.Request, eventType schema.AuditEventType) *schema.AuditEvent {
+	event := &schema.AuditEvent{
+		Type:      eventType,
+		ActorID:   GetAuthUserID(r),
+		IP:        GetClientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+	if auth := GetAuth(r); auth != nil {
+		event.ImpersonatorID = auth.ImpersonatorID
+	}
+	return event
+}
+
+// Audit operations
+
+// RecordAudit stores an audit event, dropping the oldest events above AUDIT_LOG_MAX_EVENTS
+func (db *Database) RecordAudit(event *schema.AuditEvent) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	event.ID = GenerateID()
+	event.CreatedAt = time.Now()
+	db.Audit = append(db.Audit, event)
+
+	maxEvents, err := strconv.Atoi(GetEnv("AUDIT_LOG_MAX_EVENTS", "5000"))
+	if err == nil && maxEvents > 0 && len(db.Audit) > maxEvents {
+		db.Audit = db.Audit[len(db.Audit)-maxEvents:]
+	}
+
+	if err := db.saveUnsafe(); err != nil {
+		log.Printf("Failed to save audit event: %v", err)
+	}
+}
+
+// ListAudit returns matching audit events, newest first
+func (db *Database) ListAudit(query *schema.AuditQuery) []*schema.AuditEvent {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	events := make([]*schema.AuditEvent, 0)
+	for i := len(db.Audit) - 1; i >= 0; i-- {
+		event := db.Audit[i]
+
+		if query.Type != "" && event.Type != query.Type {
+			continue
+		}
+		if query.UserID != "" && event.UserID != query.UserID && event.ActorID != query.UserID &&
+			event.ImpersonatorID != query.UserID {
+			continue
+		}
+		if query.Username != "" && event.Username != query.Username {
+			continue
+		}
+		if query.IP != "" && event.IP != query.IP {
+			continue
+		}
+
+		events = append(events, event)
+		if query.Limit > 0 && len(events) >= query.Limit {
+			break
+		}
+	}
+
+	return events
+}
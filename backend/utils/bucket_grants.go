@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"sort"
+	"time"
+)
+
+// Bucket grant operations
+
+// ListBucketGrants returns the grants of a bucket, oldest first
+func (db *Database) ListBucketGrants(bucketID string) []*schema.BucketGrant {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	grants := make([]*schema.BucketGrant, 0)
+	for _, grant := range db.BucketGrants {
+		if grant.BucketID == bucketID {
+			result := *grant
+			grants = append(grants, &result)
+		}
+	}
+
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].CreatedAt.Before(grants[j].CreatedAt)
+	})
+
+	return grants
+}
+
+func (db *Database) GetBucketGrant(id string) (*schema.BucketGrant, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	grant, exists := db.BucketGrants[id]
+	if !exists {
+		return nil, errors.New("grant not found")
+	}
+
+	result := *grant
+	return &result, nil
+}
+
+// SetBucketGrant grants a user or group access to a bucket, replacing the grant
+// the subject already has for the same prefix
+func (db *Database) SetBucketGrant(bucketID string, req *schema.CreateBucketGrantRequest, createdBy string) (*schema.BucketGrant, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if bucketID == "" {
+		return nil, errors.New("bucket is required")
+	}
+	if !req.Access.IsValid() {
+		return nil, fmt.Errorf("unknown access level: %s", req.Access)
+	}
+
+	switch {
+	case req.UserID != nil && req.GroupID != nil:
+		return nil, errors.New("grant either a user or a group, not both")
+	case req.UserID != nil:
+		if _, exists := db.Users[*req.UserID]; !exists {
+			return nil, errors.New("user not found")
+		}
+	case req.GroupID != nil:
+		if _, exists := db.Groups[*req.GroupID]; !exists {
+			return nil, errors.New("group not found")
+		}
+	default:
+		return nil, errors.New("user or group is required")
+	}
+
+	now := time.Now()
+	for _, grant := range db.BucketGrants {
+		if grant.BucketID == bucketID && grant.Prefix == req.Prefix &&
+			sameSubject(grant.UserID, req.UserID) && sameSubject(grant.GroupID, req.GroupID) {
+			grant.Access = req.Access
+			grant.UpdatedAt = now
+
+			if err := db.saveUnsafe(); err != nil {
+				return nil, err
+			}
+			result := *grant
+			return &result, nil
+		}
+	}
+
+	grant := &schema.BucketGrant{
+		ID:        GenerateID(),
+		BucketID:  bucketID,
+		UserID:    req.UserID,
+		GroupID:   req.GroupID,
+		Access:    req.Access,
+		Prefix:    req.Prefix,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	db.BucketGrants[grant.ID] = grant
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *grant
+	return &result, nil
+}
+
+func (db *Database) DeleteBucketGrant(id string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.BucketGrants[id]; !exists {
+		return errors.New("grant not found")
+	}
+
+	delete(db.BucketGrants, id)
+	return db.saveUnsafe()
+}
+
+// DeleteBucketGrants removes every grant of a deleted bucket
+func (db *Database) DeleteBucketGrants(bucketID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	deleted := false
+	for grantID, grant := range db.BucketGrants {
+		if grant.BucketID == bucketID {
+			delete(db.BucketGrants, grantID)
+			deleted = true
+		}
+	}
+
+	if !deleted {
+		return nil
+	}
+	return db.saveUnsafe()
+}
+
+// GetBucketAccess decides the access a user has to an object key or listing prefix of a
+// bucket. Users with the system_admin permission have full access. Otherwise the user's
+// own grants decide, then the grants of their groups; among matching grants the one with
+// the longest prefix wins. Without a matching grant the read_buckets and write_buckets
+// permissions give read and write access to the whole bucket.
+func (db *Database) GetBucketAccess(user *schema.User, bucketID, key string) schema.BucketAccessDecision {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	if db.hasPermissionUnsafe(user, schema.PermissionSystemAdmin) {
+		return schema.BucketAccessDecision{
+			Access: schema.BucketAccessWrite,
+			Reason: "system_admin permission",
+		}
+	}
+
+	groups := db.getUserGroupsUnsafe(user.ID)
+	if grant := db.matchBucketGrantUnsafe(bucketID, key, func(grant *schema.BucketGrant) bool {
+		return grant.UserID != nil && *grant.UserID == user.ID
+	}); grant != nil {
+		return schema.BucketAccessDecision{
+			Access: grant.Access,
+			Reason: "user grant",
+			Grant:  grant,
+		}
+	}
+
+	if grant := db.matchBucketGrantUnsafe(bucketID, key, func(grant *schema.BucketGrant) bool {
+		for _, group := range groups {
+			if grant.GroupID != nil && *grant.GroupID == group.ID {
+				return true
+			}
+		}
+		return false
+	}); grant != nil {
+		return schema.BucketAccessDecision{
+			Access: grant.Access,
+			Reason: fmt.Sprintf("group grant (%s)", db.Groups[*grant.GroupID].Name),
+			Grant:  grant,
+		}
+	}
+
+	switch {
+	case db.hasPermissionUnsafe(user, schema.PermissionWriteBuckets):
+		return schema.BucketAccessDecision{
+			Access: schema.BucketAccessWrite,
+			Reason: "write_buckets permission",
+		}
+	case db.hasPermissionUnsafe(user, schema.PermissionReadBuckets):
+		return schema.BucketAccessDecision{
+			Access: schema.BucketAccessRead,
+			Reason: "read_buckets permission",
+		}
+	}
+
+	return schema.BucketAccessDecision{
+		Access: schema.BucketAccessNone,
+		Reason: "no grant or bucket permission",
+	}
+}
+
+// HasBucketAccess checks if a user may see at least part of a bucket
+func (db *Database) HasBucketAccess(user *schema.User, bucketID string) bool {
+	if db.GetBucketAccess(user, bucketID, "").Access != schema.BucketAccessNone {
+		return true
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	groups := db.getUserGroupsUnsafe(user.ID)
+	for _, grant := range db.BucketGrants {
+		if grant.BucketID != bucketID || grant.Access == schema.BucketAccessNone {
+			continue
+		}
+		if grant.UserID != nil && *grant.UserID == user.ID {
+			return true
+		}
+		for _, group := range groups {
+			if grant.GroupID != nil && *grant.GroupID == group.ID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchBucketGrantUnsafe returns the most specific grant of a subject covering a key.
+// Grants with the same prefix are resolved in favour of the more permissive one.
+func (db *Database) matchBucketGrantUnsafe(bucketID, key string, subject func(grant *schema.BucketGrant) bool) *schema.BucketGrant {
+	var match *schema.BucketGrant
+	for _, grant := range db.BucketGrants {
+		if grant.BucketID != bucketID || !grant.Matches(key) || !subject(grant) {
+			continue
+		}
+		if match == nil || len(grant.Prefix) > len(match.Prefix) ||
+			(len(grant.Prefix) == len(match.Prefix) && !match.Access.Allows(grant.Access)) {
+			match = grant
+		}
+	}
+
+	if match == nil {
+		return nil
+	}
+	result := *match
+	return &result
+}
+
+func sameSubject(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -47,3 +47,7 @@ func (c *CacheManager) Get(key string) interface{} {
 func (c *CacheManager) IsExpired(entry CacheEntry) bool {
 	return entry.expiresAt.Before(time.Now())
 }
+
+func (c *CacheManager) Delete(key string) {
+	c.cache.Delete(key)
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CSRFHeader carries the CSRF token on state-changing requests
+const CSRFHeader = "X-CSRF-Token"
+
+// csrfCookieName is readable by the UI so it can echo the token in CSRFHeader
+const csrfCookieName = "csrf_token"
+
+// IssueCSRFToken generates a new CSRF token for the session, e.g. after login
+func IssueCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	Session.Set(r, "csrf_token", token)
+	setCSRFCookie(w, token)
+	return token, nil
+}
+
+// EnsureCSRFToken returns the CSRF token of the session, issuing one if needed
+func EnsureCSRFToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if token, ok := Session.Get(r, "csrf_token").(string); ok && token != "" {
+		setCSRFCookie(w, token)
+		return token, nil
+	}
+	return IssueCSRFToken(w, r)
+}
+
+// VerifyCSRFToken checks the request header against the token stored in the session
+func VerifyCSRFToken(r *http.Request) bool {
+	expected, ok := Session.Get(r, "csrf_token").(string)
+	if !ok || expected == "" {
+		return false
+	}
+
+	token := r.Header.Get(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+func setCSRFCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     getCookiePath(),
+		MaxAge:   int(SessionLifetime.Seconds()),
+		Secure:   GetCookieSecure(),
+		SameSite: GetCookieSameSite(),
+	})
+}
+
+// GetCookieSameSite returns the SameSite mode for cookies from SESSION_COOKIE_SAMESITE
+func GetCookieSameSite() http.SameSite {
+	switch strings.ToLower(GetEnv("SESSION_COOKIE_SAMESITE", "lax")) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
+
+// GetCookieSecure reports whether cookies are only sent over HTTPS. Browsers
+// reject SameSite=None cookies without the Secure attribute.
+func GetCookieSecure() bool {
+	secure, _ := strconv.ParseBool(GetEnv("SESSION_COOKIE_SECURE", "false"))
+	return secure || GetCookieSameSite() == http.SameSiteNoneMode
+}
+
+func getCookiePath() string {
+	if basePath := os.Getenv("BASE_PATH"); basePath != "" {
+		return basePath
+	}
+	return "/"
+}
@@ -2,13 +2,16 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"khairul169/garage-webui/schema"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -17,16 +20,42 @@ import (
 )
 
 type Database struct {
-	Users    map[string]*schema.User    `json:"users"`
-	Tenants  map[string]*schema.Tenant  `json:"tenants"`
-	Sessions map[string]*schema.Session `json:"sessions"`
-	mutex    sync.RWMutex
+	Users               map[string]*schema.User               `json:"users"`
+	Tenants             map[string]*schema.Tenant             `json:"tenants"`
+	Sessions            map[string]*schema.Session            `json:"sessions"`
+	AccessTokens        map[string]*schema.AccessToken        `json:"access_tokens"`
+	TwoFactor           map[string]*schema.TwoFactor          `json:"two_factor"`
+	PasswordResets      map[string]*schema.PasswordReset      `json:"password_resets"`
+	WebAuthnCredentials map[string]*schema.WebAuthnCredential `json:"webauthn_credentials"`
+	Groups              map[string]*schema.Group              `json:"groups"`
+	Invitations         map[string]*schema.Invitation         `json:"invitations"`
+	Elevations          map[string]*schema.ElevationRequest   `json:"elevations"`
+	Roles               map[string]*schema.RoleDefinition     `json:"roles"`
+	BucketOwners        map[string]*schema.ResourceOwner      `json:"bucket_owners"`
+	KeyOwners           map[string]*schema.ResourceOwner      `json:"key_owners"`
+	BucketGrants        map[string]*schema.BucketGrant        `json:"bucket_grants"`
+	Approvals           map[string]*schema.ApprovalRequest    `json:"approvals"`
+	Settings            schema.SecuritySettings               `json:"settings"`
+	Audit               []*schema.AuditEvent                  `json:"audit"`
+	mutex               sync.RWMutex
 }
 
 var DB = &Database{
-	Users:    make(map[string]*schema.User),
-	Tenants:  make(map[string]*schema.Tenant),
-	Sessions: make(map[string]*schema.Session),
+	Users:               make(map[string]*schema.User),
+	Tenants:             make(map[string]*schema.Tenant),
+	Sessions:            make(map[string]*schema.Session),
+	AccessTokens:        make(map[string]*schema.AccessToken),
+	TwoFactor:           make(map[string]*schema.TwoFactor),
+	PasswordResets:      make(map[string]*schema.PasswordReset),
+	WebAuthnCredentials: make(map[string]*schema.WebAuthnCredential),
+	Groups:              make(map[string]*schema.Group),
+	Invitations:         make(map[string]*schema.Invitation),
+	Elevations:          make(map[string]*schema.ElevationRequest),
+	Roles:               make(map[string]*schema.RoleDefinition),
+	BucketOwners:        make(map[string]*schema.ResourceOwner),
+	KeyOwners:           make(map[string]*schema.ResourceOwner),
+	BucketGrants:        make(map[string]*schema.BucketGrant),
+	Approvals:           make(map[string]*schema.ApprovalRequest),
 }
 
 func InitDatabase() error {
@@ -41,6 +70,10 @@ func InitDatabase() error {
 		return fmt.Errorf("failed to load database: %w", err)
 	}
 
+	if err := DB.CleanupExpiredSessions(); err != nil {
+		return fmt.Errorf("failed to clean up sessions: %w", err)
+	}
+
 	// Create default admin user if no users exist
 	if len(DB.Users) == 0 {
 		if err := DB.CreateDefaultAdmin(); err != nil {
@@ -120,8 +153,10 @@ func (db *Database) CreateDefaultAdmin() error {
 		PasswordHash: string(hashedPassword),
 		Role:         schema.RoleAdmin,
 		Enabled:      true,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		// The default password is public knowledge
+		MustChangePassword: true,
+		CreatedAt:          time.Now(),
+		UpdatedAt:          time.Now(),
 	}
 
 	db.Users[admin.ID] = admin
@@ -159,22 +194,28 @@ func (db *Database) CreateUser(req *schema.CreateUserRequest) (*schema.User, err
 		}
 	}
 
-	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
-	if err != nil {
+	if !db.roleExistsUnsafe(req.Role) {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, req.Role)
+	}
+
+	if err := PasswordPolicy.Validate(req.Password, req.Username); err != nil {
 		return nil, err
 	}
 
 	user := &schema.User{
-		ID:           GenerateID(),
-		Username:     req.Username,
-		Email:        req.Email,
-		PasswordHash: string(hashedPassword),
-		Role:         req.Role,
-		TenantID:     req.TenantID,
-		Enabled:      req.Enabled,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		ID:                 GenerateID(),
+		Username:           req.Username,
+		Email:              req.Email,
+		Role:               req.Role,
+		TenantID:           req.TenantID,
+		Enabled:            req.Enabled,
+		MustChangePassword: req.MustChangePassword,
+		CreatedAt:          time.Now(),
+	}
+
+	// Hash password
+	if err := setPasswordUnsafe(user, req.Password); err != nil {
+		return nil, err
 	}
 
 	db.Users[user.ID] = user
@@ -202,6 +243,11 @@ func (db *Database) GetUserByUsername(username string) (*schema.User, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
+	// Invited users have no username until they accept
+	if username == "" {
+		return nil, errors.New("user not found")
+	}
+
 	for _, user := range db.Users {
 		if user.Username == username {
 			return user, nil
@@ -211,6 +257,78 @@ func (db *Database) GetUserByUsername(username string) (*schema.User, error) {
 	return nil, errors.New("user not found")
 }
 
+// UpsertExternalUser finds a user provisioned by an external identity provider
+// or creates it just-in-time, keeping its role and tenant in sync with the provider
+func (db *Database) UpsertExternalUser(source, externalID, username, email string, role schema.Role, tenantID *string) (*schema.User, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, user := range db.Users {
+		if user.AuthSource == source && user.ExternalID == externalID {
+			if !user.Enabled {
+				return nil, errors.New("user account is disabled")
+			}
+
+			user.Email = email
+			if !user.SCIMProvisioned {
+				user.Role = role
+				user.TenantID = tenantID
+			}
+			user.UpdatedAt = time.Now()
+
+			if err := db.saveUnsafe(); err != nil {
+				return nil, err
+			}
+			return user, nil
+		}
+	}
+
+	// Never link to an existing account by username, which could be a local admin.
+	// Accounts provisioned by the identity provider through SCIM are the exception.
+	for _, user := range db.Users {
+		if user.Username != username {
+			continue
+		}
+		if !user.SCIMProvisioned || user.ExternalID != "" {
+			return nil, errors.New("username already exists")
+		}
+		if !user.Enabled {
+			return nil, errors.New("user account is disabled")
+		}
+
+		// SCIM stays authoritative for the role and tenant of the account
+		user.AuthSource = source
+		user.ExternalID = externalID
+		user.UpdatedAt = time.Now()
+
+		if err := db.saveUnsafe(); err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+
+	user := &schema.User{
+		ID:         GenerateID(),
+		Username:   username,
+		Email:      email,
+		Role:       role,
+		TenantID:   tenantID,
+		Enabled:    true,
+		AuthSource: source,
+		ExternalID: externalID,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	db.Users[user.ID] = user
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (db *Database) UpdateUser(id string, req *schema.UpdateUserRequest) (*schema.User, error) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
@@ -220,6 +338,25 @@ func (db *Database) UpdateUser(id string, req *schema.UpdateUserRequest) (*schem
 		return nil, errors.New("user not found")
 	}
 
+	// Validate the new password before applying any change
+	if req.Password != nil {
+		if user.AuthSource != "" && user.AuthSource != schema.AuthSourceLocal {
+			return nil, errors.New("password is managed by an external identity provider")
+		}
+
+		username := user.Username
+		if req.Username != nil {
+			username = *req.Username
+		}
+		if err := PasswordPolicy.Validate(*req.Password, username); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Role != nil && !db.roleExistsUnsafe(*req.Role) {
+		return nil, fmt.Errorf("%w: %s", ErrRoleNotFound, *req.Role)
+	}
+
 	if req.Username != nil {
 		user.Username = *req.Username
 	}
@@ -227,11 +364,12 @@ func (db *Database) UpdateUser(id string, req *schema.UpdateUserRequest) (*schem
 		user.Email = *req.Email
 	}
 	if req.Password != nil {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(*req.Password), bcrypt.DefaultCost)
-		if err != nil {
+		if err := setPasswordUnsafe(user, *req.Password); err != nil {
 			return nil, err
 		}
-		user.PasswordHash = string(hashedPassword)
+	}
+	if req.MustChangePassword != nil {
+		user.MustChangePassword = *req.MustChangePassword
 	}
 	if req.Role != nil {
 		user.Role = *req.Role
@@ -241,6 +379,11 @@ func (db *Database) UpdateUser(id string, req *schema.UpdateUserRequest) (*schem
 	}
 	if req.Enabled != nil {
 		user.Enabled = *req.Enabled
+
+		// Disabled users are logged out everywhere
+		if !user.Enabled {
+			db.deleteUserSessionsUnsafe(id)
+		}
 	}
 
 	user.UpdatedAt = time.Now()
@@ -261,6 +404,58 @@ func (db *Database) DeleteUser(id string) error {
 	}
 
 	delete(db.Users, id)
+
+	delete(db.TwoFactor, id)
+
+	db.deleteUserSessionsUnsafe(id)
+
+	for resetID, reset := range db.PasswordResets {
+		if reset.UserID == id {
+			delete(db.PasswordResets, resetID)
+		}
+	}
+
+	for invitationID, invitation := range db.Invitations {
+		if invitation.UserID == id {
+			delete(db.Invitations, invitationID)
+		}
+	}
+
+	for elevationID, elevation := range db.Elevations {
+		if elevation.UserID == id {
+			delete(db.Elevations, elevationID)
+		}
+	}
+
+	for credentialID, credential := range db.WebAuthnCredentials {
+		if credential.UserID == id {
+			delete(db.WebAuthnCredentials, credentialID)
+		}
+	}
+
+	for _, group := range db.Groups {
+		members := make([]string, 0, len(group.Members))
+		for _, member := range group.Members {
+			if member != id {
+				members = append(members, member)
+			}
+		}
+		group.Members = members
+	}
+
+	for grantID, grant := range db.BucketGrants {
+		if grant.UserID != nil && *grant.UserID == id {
+			delete(db.BucketGrants, grantID)
+		}
+	}
+
+	// Revoke the user's access tokens
+	for tokenID, accessToken := range db.AccessTokens {
+		if accessToken.UserID == id {
+			delete(db.AccessTokens, tokenID)
+		}
+	}
+
 	return db.saveUnsafe()
 }
 
@@ -321,6 +516,19 @@ func (db *Database) GetTenant(id string) (*schema.Tenant, error) {
 	return tenant, nil
 }
 
+func (db *Database) GetTenantByName(name string) (*schema.Tenant, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	for _, tenant := range db.Tenants {
+		if tenant.Name == name {
+			return tenant, nil
+		}
+	}
+
+	return nil, errors.New("tenant not found")
+}
+
 func (db *Database) UpdateTenant(id string, req *schema.UpdateTenantRequest) (*schema.Tenant, error) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
@@ -367,6 +575,22 @@ func (db *Database) DeleteTenant(id string) error {
 	}
 
 	delete(db.Tenants, id)
+
+	// Groups bound to the tenant no longer grant one
+	for _, group := range db.Groups {
+		if group.TenantID != nil && *group.TenantID == id {
+			group.TenantID = nil
+		}
+	}
+
+	// Its buckets and keys return to the shared pool
+	for _, owners := range []map[string]*schema.ResourceOwner{db.BucketOwners, db.KeyOwners} {
+		for resourceID, owner := range owners {
+			if owner.TenantID == id {
+				delete(owners, resourceID)
+			}
+		}
+	}
 	return db.saveUnsafe()
 }
 
@@ -383,7 +607,7 @@ func (db *Database) ListTenants() ([]*schema.Tenant, error) {
 }
 
 // Session operations
-func (db *Database) CreateSession(userID string) (*schema.Session, error) {
+func (db *Database) CreateSession(userID, ipAddress, userAgent string) (*schema.Session, error) {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
@@ -392,12 +616,17 @@ func (db *Database) CreateSession(userID string) (*schema.Session, error) {
 		return nil, err
 	}
 
+	now := time.Now()
 	session := &schema.Session{
-		ID:        GenerateID(),
-		UserID:    userID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hours expiry
-		CreatedAt: time.Now(),
+		ID:             GenerateID(),
+		UserID:         userID,
+		Token:          token,
+		TokenHash:      HashToken(token),
+		IPAddress:      ipAddress,
+		UserAgent:      userAgent,
+		LastActivityAt: now,
+		ExpiresAt:      now.Add(SessionLifetime),
+		CreatedAt:      now,
 	}
 
 	db.Sessions[session.ID] = session
@@ -409,12 +638,32 @@ func (db *Database) CreateSession(userID string) (*schema.Session, error) {
 	return session, nil
 }
 
+func (db *Database) GetSession(id string) (*schema.Session, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	session, exists := db.Sessions[id]
+	if !exists {
+		return nil, errors.New("session not found")
+	}
+	if time.Now().After(session.ExpiresAt) {
+		return nil, errors.New("session expired")
+	}
+
+	return session, nil
+}
+
 func (db *Database) GetSessionByToken(token string) (*schema.Session, error) {
 	db.mutex.RLock()
 	defer db.mutex.RUnlock()
 
+	if token == "" {
+		return nil, errors.New("session not found")
+	}
+
+	tokenHash := HashToken(token)
 	for _, session := range db.Sessions {
-		if session.Token == token {
+		if session.TokenHash == tokenHash {
 			if time.Now().After(session.ExpiresAt) {
 				return nil, errors.New("session expired")
 			}
@@ -425,28 +674,143 @@ func (db *Database) GetSessionByToken(token string) (*schema.Session, error) {
 	return nil, errors.New("session not found")
 }
 
+// ListSessions returns the active sessions of a user, or of all users when userID is empty,
+// most recently used first
+func (db *Database) ListSessions(userID string) ([]schema.Session, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	now := time.Now()
+	sessions := make([]schema.Session, 0)
+	for _, session := range db.Sessions {
+		if (userID == "" || session.UserID == userID) && now.Before(session.ExpiresAt) {
+			sessions = append(sessions, sanitizeSession(session))
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivityAt.After(sessions[j].LastActivityAt)
+	})
+
+	return sessions, nil
+}
+
+// TouchSession records activity on a session along with the client it came from
+func (db *Database) TouchSession(id, ipAddress, userAgent string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	session, exists := db.Sessions[id]
+	if !exists {
+		return errors.New("session not found")
+	}
+
+	now := time.Now()
+	if now.Sub(session.LastActivityAt) < sessionTouchInterval &&
+		session.IPAddress == ipAddress && session.UserAgent == userAgent {
+		return nil
+	}
+
+	session.LastActivityAt = now
+	session.IPAddress = ipAddress
+	session.UserAgent = userAgent
+	return db.saveUnsafe()
+}
+
 func (db *Database) DeleteSession(id string) error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
+	if _, exists := db.Sessions[id]; !exists {
+		return errors.New("session not found")
+	}
+
 	delete(db.Sessions, id)
 	return db.saveUnsafe()
 }
 
+// DeleteUserSessions revokes all sessions of a user
+func (db *Database) DeleteUserSessions(userID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.deleteUserSessionsUnsafe(userID)
+	return db.saveUnsafe()
+}
+
+// DeleteOtherSessions revokes all sessions of a user except the given one
+func (db *Database) DeleteOtherSessions(userID, keepSessionID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for id, session := range db.Sessions {
+		if session.UserID == userID && id != keepSessionID {
+			delete(db.Sessions, id)
+		}
+	}
+
+	return db.saveUnsafe()
+}
+
+func (db *Database) deleteUserSessionsUnsafe(userID string) {
+	for id, session := range db.Sessions {
+		if session.UserID == userID {
+			delete(db.Sessions, id)
+		}
+	}
+}
+
+// StartSessionCleanup periodically removes expired session records
+func (db *Database) StartSessionCleanup() {
+	ticker := time.NewTicker(getCleanupInterval())
+	go func() {
+		for range ticker.C {
+			if err := db.CleanupExpiredSessions(); err != nil {
+				log.Printf("Failed to clean up expired sessions: %v", err)
+			}
+		}
+	}()
+}
+
 func (db *Database) CleanupExpiredSessions() error {
 	db.mutex.Lock()
 	defer db.mutex.Unlock()
 
 	now := time.Now()
+	removed := 0
 	for id, session := range db.Sessions {
 		if now.After(session.ExpiresAt) {
 			delete(db.Sessions, id)
+			removed++
 		}
 	}
+	for id, reset := range db.PasswordResets {
+		if now.After(reset.ExpiresAt) {
+			delete(db.PasswordResets, id)
+			removed++
+		}
+	}
+	for id, invitation := range db.Invitations {
+		if now.After(invitation.ExpiresAt) {
+			delete(db.Invitations, id)
+			removed++
+		}
+	}
+
+	if removed == 0 {
+		return nil
+	}
 
 	return db.saveUnsafe()
 }
 
+// sanitizeSession returns a copy of the session without its token hash
+func sanitizeSession(session *schema.Session) schema.Session {
+	result := *session
+	result.TokenHash = ""
+	return result
+}
+
 // Utility functions
 func GenerateID() string {
 	bytes := make([]byte, 16)
@@ -462,6 +826,12 @@ func GenerateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// HashToken returns the SHA-256 digest of a token, used to store tokens at rest
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // AuthenticateUser validates credentials and returns user
 func (db *Database) AuthenticateUser(username, password string) (*schema.User, error) {
 	user, err := db.GetUserByUsername(username)
@@ -473,6 +843,11 @@ func (db *Database) AuthenticateUser(username, password string) (*schema.User, e
 		return nil, errors.New("user account is disabled")
 	}
 
+	// Accounts from external identity providers have no local password
+	if user.AuthSource != "" && user.AuthSource != schema.AuthSourceLocal {
+		return nil, errors.New("invalid credentials")
+	}
+
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
 		return nil, errors.New("invalid credentials")
 	}
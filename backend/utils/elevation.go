@@ -0,0 +1,281 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+const elevationExpiryInterval = time.Minute
+
+// Elevation operations
+
+// RequestElevation files a pending request to temporarily elevate a user's role
+func (db *Database) RequestElevation(userID string, req *schema.CreateElevationRequest) (*schema.ElevationRequest, error) {
+	if !db.RoleExists(req.Role) {
+		return nil, errors.New("invalid role: " + string(req.Role))
+	}
+
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	duration, err := parseRequestedDuration(req.Duration,
+		getLoginDuration("ELEVATION_DEFAULT_DURATION", time.Hour),
+		getLoginDuration("ELEVATION_MAX_DURATION", 4*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+	if user.Role != schema.RoleUser && user.Role != schema.RoleTenantAdmin {
+		return nil, errors.New("only user and tenant_admin accounts can request an elevation")
+	}
+	if req.Role == user.Role {
+		return nil, errors.New("the requested role is already assigned")
+	}
+
+	for _, elevation := range db.Elevations {
+		if elevation.UserID != userID {
+			continue
+		}
+		if elevation.Status == schema.ElevationPending || isElevationActive(elevation) {
+			return nil, errors.New("an elevation request is already pending or active")
+		}
+	}
+
+	now := time.Now()
+	elevation := &schema.ElevationRequest{
+		ID:        GenerateID(),
+		UserID:    userID,
+		Role:      req.Role,
+		Reason:    reason,
+		Duration:  duration.String(),
+		Status:    schema.ElevationPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	db.Elevations[elevation.ID] = elevation
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *elevation
+	return &result, nil
+}
+
+// GetElevation returns an elevation request by ID
+func (db *Database) GetElevation(id string) (*schema.ElevationRequest, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	elevation, exists := db.Elevations[id]
+	if !exists {
+		return nil, errors.New("elevation request not found")
+	}
+
+	result := *elevation
+	return &result, nil
+}
+
+// ListElevations returns elevation requests, newest first. An empty userID returns the requests of all users.
+func (db *Database) ListElevations(userID string, status schema.ElevationStatus) []schema.ElevationRequest {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	elevations := make([]schema.ElevationRequest, 0)
+	for _, elevation := range db.Elevations {
+		if userID != "" && elevation.UserID != userID {
+			continue
+		}
+		if status != "" && elevation.Status != status {
+			continue
+		}
+		elevations = append(elevations, *elevation)
+	}
+
+	sort.Slice(elevations, func(i, j int) bool {
+		return elevations[i].CreatedAt.After(elevations[j].CreatedAt)
+	})
+
+	return elevations
+}
+
+// ApproveElevation grants a pending request. The elevated role applies from now until the requested duration elapses.
+func (db *Database) ApproveElevation(id, reviewerID string) (*schema.ElevationRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	elevation, err := db.reviewElevationUnsafe(id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, exists := db.Users[elevation.UserID]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+
+	duration, err := time.ParseDuration(elevation.Duration)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	elevation.Status = schema.ElevationApproved
+	elevation.ReviewedBy = reviewerID
+	elevation.ReviewedAt = &now
+	elevation.ExpiresAt = &expiresAt
+	elevation.UpdatedAt = now
+
+	user.Elevation = &schema.Elevation{
+		RequestID: elevation.ID,
+		Role:      elevation.Role,
+		ExpiresAt: expiresAt,
+	}
+	user.UpdatedAt = now
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *elevation
+	return &result, nil
+}
+
+// DenyElevation rejects a pending request
+func (db *Database) DenyElevation(id, reviewerID string) (*schema.ElevationRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	elevation, err := db.reviewElevationUnsafe(id, reviewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	elevation.Status = schema.ElevationDenied
+	elevation.ReviewedBy = reviewerID
+	elevation.ReviewedAt = &now
+	elevation.UpdatedAt = now
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *elevation
+	return &result, nil
+}
+
+// RevokeElevation withdraws a pending request or ends an active elevation early
+func (db *Database) RevokeElevation(id string) (*schema.ElevationRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	elevation, exists := db.Elevations[id]
+	if !exists {
+		return nil, errors.New("elevation request not found")
+	}
+	if elevation.Status != schema.ElevationPending && !isElevationActive(elevation) {
+		return nil, fmt.Errorf("elevation request is already %s", elevation.Status)
+	}
+
+	now := time.Now()
+	elevation.Status = schema.ElevationRevoked
+	elevation.UpdatedAt = now
+	db.clearUserElevationUnsafe(elevation)
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *elevation
+	return &result, nil
+}
+
+// ExpireElevations reverts elevations whose duration has elapsed and returns them
+func (db *Database) ExpireElevations() ([]schema.ElevationRequest, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	now := time.Now()
+	expired := make([]schema.ElevationRequest, 0)
+	for _, elevation := range db.Elevations {
+		if elevation.Status != schema.ElevationApproved || elevation.ExpiresAt == nil || now.Before(*elevation.ExpiresAt) {
+			continue
+		}
+
+		elevation.Status = schema.ElevationExpired
+		elevation.UpdatedAt = now
+		db.clearUserElevationUnsafe(elevation)
+		expired = append(expired, *elevation)
+	}
+
+	if len(expired) == 0 {
+		return expired, nil
+	}
+
+	return expired, db.saveUnsafe()
+}
+
+// StartElevationExpiry periodically reverts expired elevations and records their expiry in the audit log
+func (db *Database) StartElevationExpiry() {
+	ticker := time.NewTicker(elevationExpiryInterval)
+	go func() {
+		for range ticker.C {
+			expired, err := db.ExpireElevations()
+			if err != nil {
+				log.Printf("Failed to expire elevations: %v", err)
+			}
+
+			for _, elevation := range expired {
+				db.RecordAudit(&schema.AuditEvent{
+					Type:    schema.AuditElevationExpired,
+					UserID:  elevation.UserID,
+					Message: fmt.Sprintf("%s elevation expired", elevation.Role),
+				})
+			}
+		}
+	}()
+}
+
+func (db *Database) reviewElevationUnsafe(id, reviewerID string) (*schema.ElevationRequest, error) {
+	elevation, exists := db.Elevations[id]
+	if !exists {
+		return nil, errors.New("elevation request not found")
+	}
+	if elevation.Status != schema.ElevationPending {
+		return nil, fmt.Errorf("elevation request is already %s", elevation.Status)
+	}
+	if elevation.UserID == reviewerID {
+		return nil, errors.New("cannot review your own elevation request")
+	}
+	return elevation, nil
+}
+
+func isElevationActive(elevation *schema.ElevationRequest) bool {
+	return elevation.Status == schema.ElevationApproved &&
+		elevation.ExpiresAt != nil && time.Now().Before(*elevation.ExpiresAt)
+}
+
+func (db *Database) clearUserElevationUnsafe(elevation *schema.ElevationRequest) {
+	user, exists := db.Users[elevation.UserID]
+	if !exists || user.Elevation == nil || user.Elevation.RequestID != elevation.ID {
+		return
+	}
+	user.Elevation = nil
+	user.UpdatedAt = time.Now()
+}
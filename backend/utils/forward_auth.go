@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// forwardAuthCacheTTL limits how often provisioned proxy users are synced to the database
+const forwardAuthCacheTTL = time.Minute
+
+// forwardAuth trusts identity headers set by an authenticating reverse proxy
+// such as Authelia or oauth2-proxy
+type forwardAuth struct{}
+
+var ForwardAuth = &forwardAuth{}
+
+// Enabled reports whether forward authentication is configured
+func (f *forwardAuth) Enabled() bool {
+	enabled, _ := strconv.ParseBool(GetEnv("FORWARD_AUTH_ENABLED", "false"))
+	return enabled && GetEnv("TRUSTED_PROXIES", "") != ""
+}
+
+// GetMapping returns the group to role and tenant mapping from the environment
+func (f *forwardAuth) GetMapping() *IdentityMapping {
+	return &IdentityMapping{
+		Roles:       ParseMappingRules(GetEnv("FORWARD_AUTH_ROLE_MAPPING", "")),
+		Tenants:     ParseMappingRules(GetEnv("FORWARD_AUTH_TENANT_MAPPING", "")),
+		DefaultRole: schema.Role(GetEnv("FORWARD_AUTH_DEFAULT_ROLE", "")),
+	}
+}
+
+// GetIdentity reads the identity headers of a trusted request
+func (f *forwardAuth) GetIdentity(r *http.Request) (*ExternalIdentity, bool) {
+	if !f.Enabled() || !IsTrustedProxy(r) {
+		return nil, false
+	}
+
+	username := strings.TrimSpace(r.Header.Get(GetEnv("FORWARD_AUTH_USER_HEADER", "Remote-User")))
+	if username == "" {
+		return nil, false
+	}
+
+	identity := &ExternalIdentity{
+		Subject:  username,
+		Username: username,
+		Email:    strings.TrimSpace(r.Header.Get(GetEnv("FORWARD_AUTH_EMAIL_HEADER", "Remote-Email"))),
+		Groups:   splitList(r.Header.Get(GetEnv("FORWARD_AUTH_GROUPS_HEADER", "Remote-Groups"))),
+	}
+
+	return identity, true
+}
+
+// Provision matches or creates the user for a proxy identity, keeping its role
+// and tenant in sync with the groups sent by the proxy
+func (f *forwardAuth) Provision(identity *ExternalIdentity) (*schema.User, error) {
+	cacheKey := "forward-auth:" + HashToken(identity.Username+"\n"+identity.Email+"\n"+strings.Join(identity.Groups, ","))
+	if userID, ok := Cache.Get(cacheKey).(string); ok {
+		user, err := DB.GetUser(userID)
+		if err == nil && user.Enabled {
+			return user, nil
+		}
+		Cache.Delete(cacheKey)
+	}
+
+	role, tenantID, err := f.GetMapping().Resolve(identity.Groups, identity.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := DB.UpsertExternalUser(schema.AuthSourceProxy, identity.Subject, identity.Username, identity.Email, role, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Enabled {
+		return nil, errors.New("user account is disabled")
+	}
+
+	Cache.Set(cacheKey, user.ID, forwardAuthCacheTTL)
+	return user, nil
+}
@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Group operations
+
+// CreateGroup stores a new group. The ID and timestamps are assigned here.
+func (db *Database) CreateGroup(group *schema.Group) (*schema.Group, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	created := *group
+	created.Name = strings.TrimSpace(created.Name)
+	if created.Name == "" {
+		return nil, errors.New("group name is required")
+	}
+
+	for _, other := range db.Groups {
+		if strings.EqualFold(other.Name, created.Name) {
+			return nil, ErrSCIMConflict
+		}
+	}
+
+	created.Members = uniqueStrings(created.Members)
+	if err := db.checkMembersUnsafe(created.Members); err != nil {
+		return nil, err
+	}
+	if err := db.checkGroupAccessUnsafe(&created); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	created.ID = GenerateID()
+	created.CreatedAt = now
+	created.UpdatedAt = now
+	db.Groups[created.ID] = &created
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := created
+	return &result, nil
+}
+
+func (db *Database) GetGroup(id string) (*schema.Group, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	group, exists := db.Groups[id]
+	if !exists {
+		return nil, errors.New("group not found")
+	}
+
+	result := *group
+	return &result, nil
+}
+
+// ListGroups returns all groups ordered by name
+func (db *Database) ListGroups() []*schema.Group {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	groups := make([]*schema.Group, 0, len(db.Groups))
+	for _, group := range db.Groups {
+		result := *group
+		groups = append(groups, &result)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
+// GetUserGroups returns the groups a user belongs to
+func (db *Database) GetUserGroups(userID string) []*schema.Group {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.getUserGroupsUnsafe(userID)
+}
+
+func (db *Database) getUserGroupsUnsafe(userID string) []*schema.Group {
+	groups := make([]*schema.Group, 0)
+	for _, group := range db.Groups {
+		if group.HasMember(userID) {
+			result := *group
+			groups = append(groups, &result)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Name < groups[j].Name
+	})
+
+	return groups
+}
+
+// GetUserTenantID returns the tenant of a user. Users without a tenant of their own
+// inherit the tenant of their first group, by name, that is bound to one.
+func (db *Database) GetUserTenantID(user *schema.User) *string {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.getUserTenantIDUnsafe(user)
+}
+
+func (db *Database) getUserTenantIDUnsafe(user *schema.User) *string {
+	if user.TenantID != nil && *user.TenantID != "" {
+		return user.TenantID
+	}
+
+	for _, group := range db.getUserGroupsUnsafe(user.ID) {
+		if group.TenantID != nil {
+			return group.TenantID
+		}
+	}
+	return nil
+}
+
+// UpdateGroup applies changes to a group and returns it with the users whose membership changed
+func (db *Database) UpdateGroup(id string, update func(group *schema.Group) error) (*schema.Group, []string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	group, exists := db.Groups[id]
+	if !exists {
+		return nil, nil, errors.New("group not found")
+	}
+
+	updated := *group
+	updated.Members = append([]string{}, group.Members...)
+	updated.Roles = append([]schema.Role{}, group.Roles...)
+	if err := update(&updated); err != nil {
+		return nil, nil, err
+	}
+
+	updated.Name = strings.TrimSpace(updated.Name)
+	if updated.Name == "" {
+		return nil, nil, errors.New("group name is required")
+	}
+	for otherID, other := range db.Groups {
+		if otherID != id && strings.EqualFold(other.Name, updated.Name) {
+			return nil, nil, ErrSCIMConflict
+		}
+	}
+
+	updated.Members = uniqueStrings(updated.Members)
+	if err := db.checkMembersUnsafe(updated.Members); err != nil {
+		return nil, nil, err
+	}
+	if err := db.checkGroupAccessUnsafe(&updated); err != nil {
+		return nil, nil, err
+	}
+
+	changed := membershipChanges(group.Members, updated.Members)
+	updated.UpdatedAt = time.Now()
+	db.Groups[id] = &updated
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, nil, err
+	}
+
+	result := updated
+	return &result, changed, nil
+}
+
+// DeleteGroup removes a group and returns its former members
+func (db *Database) DeleteGroup(id string) ([]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	group, exists := db.Groups[id]
+	if !exists {
+		return nil, errors.New("group not found")
+	}
+
+	delete(db.Groups, id)
+
+	for grantID, grant := range db.BucketGrants {
+		if grant.GroupID != nil && *grant.GroupID == id {
+			delete(db.BucketGrants, grantID)
+		}
+	}
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return group.Members, nil
+}
+
+func (db *Database) checkMembersUnsafe(members []string) error {
+	for _, member := range members {
+		if _, exists := db.Users[member]; !exists {
+			return fmt.Errorf("member not found: %s", member)
+		}
+	}
+	return nil
+}
+
+// checkGroupAccessUnsafe validates the roles and tenant granted by a group
+func (db *Database) checkGroupAccessUnsafe(group *schema.Group) error {
+	roles := make([]schema.Role, 0, len(group.Roles))
+	seen := make(map[schema.Role]bool)
+	for _, role := range group.Roles {
+		if !db.roleExistsUnsafe(role) {
+			return fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	group.Roles = roles
+
+	if group.TenantID != nil && *group.TenantID == "" {
+		group.TenantID = nil
+	}
+	if group.TenantID != nil {
+		if _, exists := db.Tenants[*group.TenantID]; !exists {
+			return errors.New("tenant not found")
+		}
+	}
+	return nil
+}
+
+// membershipChanges returns the users added to or removed from a member list
+func membershipChanges(before, after []string) []string {
+	counts := make(map[string]int)
+	for _, member := range before {
+		counts[member]++
+	}
+	for _, member := range after {
+		counts[member]--
+	}
+
+	changed := []string{}
+	for member, count := range counts {
+		if count != 0 {
+			changed = append(changed, member)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"strings"
+)
+
+// IdentityMapping maps attributes from an external identity provider
+// (groups, email addresses or email domains) to a role and tenant
+type IdentityMapping struct {
+	Roles       []MappingRule
+	Tenants     []MappingRule
+	DefaultRole schema.Role
+}
+
+// MappingRule maps a group, an email address or an "@domain" suffix to a value
+type MappingRule struct {
+	Match string
+	Value string
+}
+
+// ParseMappingRules parses rules in the form "match=value;match=value".
+// Semicolons are used as separators since LDAP DNs contain commas.
+func ParseMappingRules(value string) []MappingRule {
+	rules := []MappingRule{}
+	for _, entry := range strings.Split(value, ";") {
+		idx := strings.LastIndex(entry, "=")
+		if idx <= 0 {
+			continue
+		}
+
+		match := strings.TrimSpace(entry[:idx])
+		result := strings.TrimSpace(entry[idx+1:])
+		if match == "" || result == "" {
+			continue
+		}
+		rules = append(rules, MappingRule{Match: match, Value: result})
+	}
+	return rules
+}
+
+// Resolve returns the role and tenant ID for an identity. Rules are evaluated
+// in the configured order and the first match wins.
+func (m *IdentityMapping) Resolve(groups []string, email string) (schema.Role, *string, error) {
+	role := m.DefaultRole
+	if value, ok := matchRules(m.Roles, groups, email); ok {
+		role = schema.Role(value)
+	}
+
+	if role == "" {
+		return "", nil, errors.New("no role mapping matches this account")
+	}
+	if !DB.RoleExists(role) {
+		return "", nil, errors.New("mapped role does not exist: " + string(role))
+	}
+
+	var tenantID *string
+	if value, ok := matchRules(m.Tenants, groups, email); ok {
+		tenant, err := DB.GetTenant(value)
+		if err != nil {
+			tenant, err = DB.GetTenantByName(value)
+		}
+		if err != nil {
+			return "", nil, errors.New("mapped tenant does not exist: " + value)
+		}
+		tenantID = &tenant.ID
+	}
+
+	return role, tenantID, nil
+}
+
+func matchRules(rules []MappingRule, groups []string, email string) (string, bool) {
+	email = strings.ToLower(email)
+
+	for _, rule := range rules {
+		match := strings.ToLower(rule.Match)
+
+		if strings.HasPrefix(match, "@") {
+			if email != "" && strings.HasSuffix(email, match) {
+				return rule.Value, true
+			}
+			continue
+		}
+
+		if email != "" && match == email {
+			return rule.Value, true
+		}
+
+		for _, group := range groups {
+			if strings.ToLower(group) == match {
+				return rule.Value, true
+			}
+		}
+	}
+
+	return "", false
+}
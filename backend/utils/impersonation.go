@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"strings"
+	"time"
+)
+
+var ErrImpersonationReadOnly = errors.New("impersonation sessions are read-only")
+
+// Impersonation operations
+
+// StartImpersonation makes an admin's session act as another user until the impersonation expires
+func (db *Database) StartImpersonation(sessionID, targetID string, req *schema.StartImpersonationRequest) (*schema.Impersonation, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+
+	duration, err := parseRequestedDuration(req.Duration,
+		getLoginDuration("IMPERSONATION_DEFAULT_DURATION", 15*time.Minute),
+		getLoginDuration("IMPERSONATION_MAX_DURATION", time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	session, exists := db.Sessions[sessionID]
+	if !exists {
+		return nil, errors.New("session not found")
+	}
+	if session.Impersonation.IsActive() {
+		return nil, errors.New("session is already impersonating a user")
+	}
+
+	target, exists := db.Users[targetID]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+	if target.ID == session.UserID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+	if !target.Enabled {
+		return nil, errors.New("cannot impersonate a disabled user")
+	}
+	if db.hasPermissionUnsafe(target, schema.PermissionSystemAdmin) {
+		return nil, errors.New("cannot impersonate an admin")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(duration)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+
+	session.Impersonation = &schema.Impersonation{
+		UserID:    target.ID,
+		Reason:    reason,
+		StartedAt: now,
+		ExpiresAt: expiresAt,
+	}
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *session.Impersonation
+	return &result, nil
+}
+
+// StopImpersonation returns the session to its own user
+func (db *Database) StopImpersonation(sessionID string) (*schema.Impersonation, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	session, exists := db.Sessions[sessionID]
+	if !exists {
+		return nil, errors.New("session not found")
+	}
+	if session.Impersonation == nil {
+		return nil, errors.New("session is not impersonating a user")
+	}
+
+	impersonation := session.Impersonation
+	session.Impersonation = nil
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return impersonation, nil
+}
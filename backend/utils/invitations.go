@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"strings"
+	"time"
+)
+
+var ErrInvalidInvitation = errors.New("invalid or expired invitation")
+
+// Invitation operations
+
+// InviteUser creates a pending, disabled user with a preset role and tenant and an invitation to activate it
+func (db *Database) InviteUser(req *schema.InviteUserRequest, invitedBy string) (*schema.InviteUserResponse, error) {
+	if !db.RoleExists(req.Role) {
+		return nil, errors.New("invalid role: " + string(req.Role))
+	}
+	if req.TenantID != nil && *req.TenantID != "" {
+		if _, err := db.GetTenant(*req.TenantID); err != nil {
+			return nil, err
+		}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	email := strings.TrimSpace(req.Email)
+	for _, user := range db.Users {
+		if email != "" && strings.EqualFold(user.Email, email) {
+			return nil, errors.New("email already exists")
+		}
+	}
+
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &schema.User{
+		ID:            GenerateID(),
+		Email:         email,
+		Role:          req.Role,
+		TenantID:      req.TenantID,
+		Enabled:       false,
+		InvitePending: true,
+		AuthSource:    schema.AuthSourceLocal,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+
+	invitation := &schema.Invitation{
+		ID:        GenerateID(),
+		UserID:    user.ID,
+		TokenHash: HashToken(token),
+		InvitedBy: invitedBy,
+		ExpiresAt: now.Add(getLoginDuration("INVITE_TTL", 72*time.Hour)),
+		CreatedAt: now,
+	}
+
+	db.Users[user.ID] = user
+	db.Invitations[invitation.ID] = invitation
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return &schema.InviteUserResponse{
+		User:      *user,
+		Token:     token,
+		InviteURL: buildTokenURL(GetEnv("INVITE_URL", ""), token),
+		ExpiresAt: invitation.ExpiresAt,
+	}, nil
+}
+
+// GetInvitation returns what an invitee is being invited as
+func (db *Database) GetInvitation(token string) (*schema.InvitationInfo, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	invitation, user := db.findInvitationUnsafe(token)
+	if invitation == nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	return &schema.InvitationInfo{
+		Email:     user.Email,
+		Role:      user.Role,
+		ExpiresAt: invitation.ExpiresAt,
+	}, nil
+}
+
+// AcceptInvitation activates the pending user with the chosen username and password.
+// The invitation is single-use and is removed on success.
+func (db *Database) AcceptInvitation(req *schema.AcceptInviteRequest) (*schema.User, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	invitation, user := db.findInvitationUnsafe(req.Token)
+	if invitation == nil {
+		return nil, ErrInvalidInvitation
+	}
+
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+	for _, other := range db.Users {
+		if other.Username == username {
+			return nil, errors.New("username already exists")
+		}
+	}
+
+	if err := PasswordPolicy.Validate(req.Password, username); err != nil {
+		return nil, err
+	}
+	if err := setPasswordUnsafe(user, req.Password); err != nil {
+		return nil, err
+	}
+
+	user.Username = username
+	user.Enabled = true
+	user.InvitePending = false
+	delete(db.Invitations, invitation.ID)
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (db *Database) findInvitationUnsafe(token string) (*schema.Invitation, *schema.User) {
+	if token == "" {
+		return nil, nil
+	}
+
+	tokenHash := HashToken(token)
+	for _, invitation := range db.Invitations {
+		if invitation.TokenHash != tokenHash {
+			continue
+		}
+		if time.Now().After(invitation.ExpiresAt) {
+			return nil, nil
+		}
+
+		user, exists := db.Users[invitation.UserID]
+		if !exists || !user.InvitePending {
+			return nil, nil
+		}
+		return invitation, user
+	}
+
+	return nil, nil
+}
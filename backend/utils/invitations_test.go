@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"testing"
+	"time"
+)
+
+func newInvitationTestDatabase(t *testing.T) *Database {
+	t.Setenv("DATA_DIR", t.TempDir())
+	return &Database{
+		Users:       make(map[string]*schema.User),
+		Tenants:     make(map[string]*schema.Tenant),
+		Groups:      make(map[string]*schema.Group),
+		Roles:       make(map[string]*schema.RoleDefinition),
+		Invitations: make(map[string]*schema.Invitation),
+	}
+}
+
+func TestAcceptInvitationIsSingleUse(t *testing.T) {
+	db := newInvitationTestDatabase(t)
+
+	invite, err := db.InviteUser(&schema.InviteUserRequest{Email: "alice@example.com", Role: schema.RoleUser}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.GetInvitation(invite.Token); err != nil {
+		t.Fatalf("GetInvitation() error = %v", err)
+	}
+
+	req := &schema.AcceptInviteRequest{Token: invite.Token, Username: "alice", Password: "a-Long-passw0rd!"}
+	user, err := db.AcceptInvitation(req)
+	if err != nil {
+		t.Fatalf("AcceptInvitation() error = %v", err)
+	}
+	if !user.Enabled || user.InvitePending || user.Username != "alice" {
+		t.Errorf("accepted user = %+v, want an enabled alice", user)
+	}
+
+	req.Username = "mallory"
+	if _, err := db.AcceptInvitation(req); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("second AcceptInvitation() error = %v, want %v", err, ErrInvalidInvitation)
+	}
+	if _, err := db.GetInvitation(invite.Token); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("GetInvitation() after acceptance error = %v, want %v", err, ErrInvalidInvitation)
+	}
+}
+
+func TestAcceptInvitationExpired(t *testing.T) {
+	db := newInvitationTestDatabase(t)
+
+	invite, err := db.InviteUser(&schema.InviteUserRequest{Email: "bob@example.com", Role: schema.RoleUser}, "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, invitation := range db.Invitations {
+		invitation.ExpiresAt = time.Now().Add(-time.Minute)
+	}
+
+	if _, err := db.GetInvitation(invite.Token); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("GetInvitation() error = %v, want %v", err, ErrInvalidInvitation)
+	}
+	req := &schema.AcceptInviteRequest{Token: invite.Token, Username: "bob", Password: "a-Long-passw0rd!"}
+	if _, err := db.AcceptInvitation(req); !errors.Is(err, ErrInvalidInvitation) {
+		t.Errorf("AcceptInvitation() error = %v, want %v", err, ErrInvalidInvitation)
+	}
+	if user := db.Users[invite.User.ID]; user.Enabled || !user.InvitePending {
+		t.Error("expired invitation activated the user")
+	}
+}
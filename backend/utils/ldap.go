@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"log"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+var (
+	ErrLDAPUserNotFound = errors.New("user not found in directory")
+	ErrLDAPUnavailable  = errors.New("directory unavailable")
+)
+
+type ldapClient struct{}
+
+var LDAP = &ldapClient{}
+
+// Enabled reports whether LDAP authentication is configured
+func (l *ldapClient) Enabled() bool {
+	return GetEnv("LDAP_URL", "") != "" && GetEnv("LDAP_BASE_DN", "") != ""
+}
+
+// GetMapping returns the group to role and tenant mapping from the environment
+func (l *ldapClient) GetMapping() *IdentityMapping {
+	mapping := &IdentityMapping{
+		Roles:       ParseMappingRules(GetEnv("LDAP_ROLE_MAPPING", "")),
+		Tenants:     ParseMappingRules(GetEnv("LDAP_TENANT_MAPPING", "")),
+		DefaultRole: schema.Role(GetEnv("LDAP_DEFAULT_ROLE", "")),
+	}
+
+	for i := range mapping.Roles {
+		mapping.Roles[i].Match = normalizeDN(mapping.Roles[i].Match)
+	}
+	for i := range mapping.Tenants {
+		mapping.Tenants[i].Match = normalizeDN(mapping.Tenants[i].Match)
+	}
+
+	return mapping
+}
+
+// Authenticate looks the user up with the service account, then binds as the user to check the password
+func (l *ldapClient) Authenticate(username, password string) (*ExternalIdentity, error) {
+	// An empty password would result in an unauthenticated bind, which always succeeds
+	if username == "" || password == "" {
+		return nil, errors.New("invalid credentials")
+	}
+
+	conn, err := l.connect()
+	if err != nil {
+		log.Printf("LDAP connection failed: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+	defer conn.Close()
+
+	if bindDN := GetEnv("LDAP_BIND_DN", ""); bindDN != "" {
+		if err := conn.Bind(bindDN, GetEnv("LDAP_BIND_PASSWORD", "")); err != nil {
+			log.Printf("LDAP service account bind failed: %v", err)
+			return nil, ErrLDAPUnavailable
+		}
+	}
+
+	usernameAttr := GetEnv("LDAP_USERNAME_ATTRIBUTE", "uid")
+	emailAttr := GetEnv("LDAP_EMAIL_ATTRIBUTE", "mail")
+	groupAttr := GetEnv("LDAP_GROUP_ATTRIBUTE", "memberOf")
+
+	filter := GetEnv("LDAP_USER_FILTER", fmt.Sprintf("(&(objectClass=person)(%s={username}))", usernameAttr))
+	filter = strings.ReplaceAll(filter, "{username}", ldap.EscapeFilter(username))
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		GetEnv("LDAP_BASE_DN", ""),
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		filter,
+		[]string{"dn", usernameAttr, emailAttr, groupAttr},
+		nil,
+	))
+	if err != nil {
+		log.Printf("LDAP search failed: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+
+	if len(result.Entries) == 0 {
+		return nil, ErrLDAPUserNotFound
+	}
+	if len(result.Entries) > 1 {
+		return nil, errors.New("username matches multiple directory entries")
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, errors.New("invalid credentials")
+		}
+		log.Printf("LDAP user bind failed: %v", err)
+		return nil, ErrLDAPUnavailable
+	}
+
+	identity := &ExternalIdentity{
+		Subject:  entry.DN,
+		Username: entry.GetAttributeValue(usernameAttr),
+		Email:    entry.GetAttributeValue(emailAttr),
+	}
+	if identity.Username == "" {
+		identity.Username = username
+	}
+	for _, group := range entry.GetAttributeValues(groupAttr) {
+		identity.Groups = append(identity.Groups, normalizeDN(group))
+	}
+
+	return identity, nil
+}
+
+func (l *ldapClient) connect() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: GetEnv("LDAP_INSECURE_SKIP_VERIFY", "false") == "true",
+	}
+
+	conn, err := ldap.DialURL(GetEnv("LDAP_URL", ""), ldap.DialWithTLSConfig(tlsConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	if GetEnv("LDAP_START_TLS", "false") == "true" {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+// normalizeDN formats a DN consistently so group DNs can be compared as strings
+func normalizeDN(value string) string {
+	dn, err := ldap.ParseDN(value)
+	if err != nil || len(dn.RDNs) == 0 {
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+	return strings.ToLower(dn.String())
+}
+
+// AuthenticateCredentials checks a username and password against the directory when
+// LDAP is configured, and against local accounts otherwise. Local accounts remain
+// usable as break-glass admins when a user is not in the directory or it is unreachable.
+func AuthenticateCredentials(username, password string) (*schema.User, error) {
+	if !LDAP.Enabled() {
+		return DB.AuthenticateUser(username, password)
+	}
+
+	identity, err := LDAP.Authenticate(username, password)
+	if errors.Is(err, ErrLDAPUserNotFound) || errors.Is(err, ErrLDAPUnavailable) {
+		return DB.AuthenticateUser(username, password)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role, tenantID, err := LDAP.GetMapping().Resolve(identity.Groups, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return DB.UpsertExternalUser(schema.AuthSourceLDAP, identity.Subject, identity.Username, identity.Email, role, tenantID)
+}
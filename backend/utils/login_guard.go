@@ -0,0 +1,272 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loginFailures tracks failed login attempts for a client IP
+type loginFailures struct {
+	count       int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// loginGuard protects logins against brute-force attacks by locking out
+// usernames and client IPs with exponential backoff after repeated failures
+type loginGuard struct {
+	ips    map[string]*loginFailures
+	resets map[string]*loginFailures
+	mutex  sync.Mutex
+}
+
+var LoginGuard = &loginGuard{
+	ips:    make(map[string]*loginFailures),
+	resets: make(map[string]*loginFailures),
+}
+
+var ErrLoginLocked = errors.New("too many failed login attempts, try again later")
+
+var ErrResetThrottled = errors.New("too many password reset requests, try again later")
+
+// Check returns how long the client has to wait before it may attempt to log in again
+func (g *loginGuard) Check(ip, username string) (time.Duration, bool) {
+	now := time.Now()
+
+	g.mutex.Lock()
+	if failures, exists := g.ips[ip]; exists && now.Before(failures.lockedUntil) {
+		g.mutex.Unlock()
+		return failures.lockedUntil.Sub(now), false
+	}
+	g.mutex.Unlock()
+
+	if user, err := DB.GetUserByUsername(username); err == nil && user.IsLocked() {
+		return user.LockedUntil.Sub(now), false
+	}
+
+	return 0, true
+}
+
+// RecordFailure counts a failed login for the client IP and username and records it in the audit log
+func (g *loginGuard) RecordFailure(r *http.Request, username string, reason error) {
+	ip := GetClientIP(r)
+	now := time.Now()
+	window := getLoginDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+
+	g.mutex.Lock()
+	failures, exists := g.ips[ip]
+	if !exists || now.Sub(failures.lastFailure) > window {
+		failures = &loginFailures{}
+		g.ips[ip] = failures
+	}
+	failures.count++
+	failures.lastFailure = now
+	if lockout := getLockoutDuration(failures.count, getLoginLimit("LOGIN_MAX_ATTEMPTS_PER_IP", 20)); lockout > 0 {
+		failures.lockedUntil = now.Add(lockout)
+	}
+	g.mutex.Unlock()
+
+	event := NewAuditEvent(r, schema.AuditLoginFailed)
+	event.Username = username
+	event.Message = reason.Error()
+
+	user, lockedUntil := DB.RecordLoginFailure(username, window)
+	if user != nil {
+		event.UserID = user.ID
+	}
+	DB.RecordAudit(event)
+
+	if lockedUntil != nil {
+		locked := NewAuditEvent(r, schema.AuditAccountLocked)
+		locked.UserID = user.ID
+		locked.Username = username
+		locked.Message = "locked until " + lockedUntil.Format(time.RFC3339)
+		DB.RecordAudit(locked)
+	}
+}
+
+// RecordSuccess resets the failure counters after a successful login
+func (g *loginGuard) RecordSuccess(r *http.Request, user *schema.User) {
+	g.mutex.Lock()
+	delete(g.ips, GetClientIP(r))
+	g.mutex.Unlock()
+
+	DB.ResetLoginFailures(user.ID)
+
+	event := NewAuditEvent(r, schema.AuditLoginSucceeded)
+	event.UserID = user.ID
+	event.Username = user.Username
+	DB.RecordAudit(event)
+}
+
+// RecordPasswordReset counts a password reset request for the client IP and username
+// and reports whether it is within PASSWORD_RESET_MAX_PER_IP and PASSWORD_RESET_MAX_PER_USER
+// for the PASSWORD_RESET_WINDOW. Throttled requests are not counted.
+func (g *loginGuard) RecordPasswordReset(ip, username string) bool {
+	now := time.Now()
+	window := getLoginDuration("PASSWORD_RESET_WINDOW", time.Hour)
+	limits := map[string]int{
+		"ip:" + ip:                          getLoginLimit("PASSWORD_RESET_MAX_PER_IP", 10),
+		"user:" + strings.ToLower(username): getLoginLimit("PASSWORD_RESET_MAX_PER_USER", 3),
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for key, limit := range limits {
+		if requests, exists := g.resets[key]; exists && now.Sub(requests.lastFailure) <= window && requests.count >= limit {
+			return false
+		}
+	}
+
+	for key := range limits {
+		requests, exists := g.resets[key]
+		if !exists || now.Sub(requests.lastFailure) > window {
+			requests = &loginFailures{}
+			g.resets[key] = requests
+		}
+		requests.count++
+		requests.lastFailure = now
+	}
+	return true
+}
+
+// Cleanup periodically drops IP failure and password reset counters outside their window
+func (g *loginGuard) Cleanup() {
+	window := getLoginDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+	resetWindow := getLoginDuration("PASSWORD_RESET_WINDOW", time.Hour)
+	ticker := time.NewTicker(window)
+	go func() {
+		for range ticker.C {
+			g.mutex.Lock()
+			now := time.Now()
+			for ip, failures := range g.ips {
+				if now.Sub(failures.lastFailure) > window && now.After(failures.lockedUntil) {
+					delete(g.ips, ip)
+				}
+			}
+			for key, requests := range g.resets {
+				if now.Sub(requests.lastFailure) > resetWindow {
+					delete(g.resets, key)
+				}
+			}
+			g.mutex.Unlock()
+		}
+	}()
+}
+
+// RecordLoginFailure increments the failed login counter of a user and locks
+// the account once the limit is reached. It returns the user, if it exists,
+// and the lockout expiry if the account has been locked.
+func (db *Database) RecordLoginFailure(username string, window time.Duration) (*schema.User, *time.Time) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	var user *schema.User
+	for _, u := range db.Users {
+		if u.Username == username {
+			user = u
+			break
+		}
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	now := time.Now()
+	if user.LastFailedLogin != nil && now.Sub(*user.LastFailedLogin) > window && !user.IsLocked() {
+		user.FailedLoginAttempts = 0
+	}
+	user.FailedLoginAttempts++
+	user.LastFailedLogin = &now
+
+	var lockedUntil *time.Time
+	if lockout := getLockoutDuration(user.FailedLoginAttempts, getLoginLimit("LOGIN_MAX_ATTEMPTS", 5)); lockout > 0 {
+		until := now.Add(lockout)
+		user.LockedUntil = &until
+		lockedUntil = &until
+	}
+
+	db.saveUnsafe()
+	return user, lockedUntil
+}
+
+// ResetLoginFailures clears the failed login counter and any lockout of a user
+func (db *Database) ResetLoginFailures(userID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	if user.FailedLoginAttempts == 0 && user.LockedUntil == nil {
+		return nil
+	}
+
+	user.FailedLoginAttempts = 0
+	user.LastFailedLogin = nil
+	user.LockedUntil = nil
+
+	return db.saveUnsafe()
+}
+
+// getLockoutDuration doubles the lockout for every failure past the limit
+func getLockoutDuration(failures, limit int) time.Duration {
+	if limit <= 0 || failures < limit {
+		return 0
+	}
+
+	base := getLoginDuration("LOGIN_LOCKOUT_DURATION", time.Minute)
+	max := getLoginDuration("LOGIN_MAX_LOCKOUT_DURATION", time.Hour)
+
+	lockout := base
+	for i := limit; i < failures && lockout < max; i++ {
+		lockout *= 2
+	}
+	if lockout > max {
+		lockout = max
+	}
+
+	return lockout
+}
+
+func getLoginLimit(key string, defaultValue int) int {
+	value, err := strconv.Atoi(GetEnv(key, ""))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getLoginDuration(key string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(GetEnv(key, ""))
+	if err != nil || value <= 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// parseRequestedDuration parses a user supplied duration, falling back to defaultValue when empty
+// and rejecting anything longer than maxValue
+func parseRequestedDuration(value string, defaultValue, maxValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return min(defaultValue, maxValue), nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, errors.New("invalid duration: " + value)
+	}
+	if duration > maxValue {
+		return 0, fmt.Errorf("duration must not exceed %s", maxValue)
+	}
+	return duration, nil
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// mailer sends notification emails through the SMTP server configured in SMTP_*
+type mailer struct{}
+
+var Mail = &mailer{}
+
+func (m *mailer) Enabled() bool {
+	return GetEnv("SMTP_HOST", "") != ""
+}
+
+// Send delivers a plain text email. STARTTLS is used when the server offers it.
+func (m *mailer) Send(to, subject, body string) error {
+	host := GetEnv("SMTP_HOST", "")
+	addr := net.JoinHostPort(host, GetEnv("SMTP_PORT", "587"))
+	from := GetEnv("SMTP_FROM", "garage-webui@"+host)
+
+	var auth smtp.Auth
+	if username := GetEnv("SMTP_USERNAME", ""); username != "" {
+		auth = smtp.PlainAuth("", username, GetEnv("SMTP_PASSWORD", ""), host)
+	}
+
+	headers := []string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	}
+	message := strings.Join(headers, "\r\n") + "\r\n\r\n" + strings.ReplaceAll(body, "\n", "\r\n")
+
+	if err := smtp.SendMail(addr, auth, from, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"strings"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ExternalIdentity is a user identity asserted by an external identity provider
+type ExternalIdentity struct {
+	Subject  string
+	Username string
+	Email    string
+	Groups   []string
+}
+
+// OIDCLoginRequest holds the values needed to start and later verify an authorization request
+type OIDCLoginRequest struct {
+	URL      string
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+type oidcClient struct {
+	mutex    sync.Mutex
+	provider *oidc.Provider
+}
+
+var OIDC = &oidcClient{}
+
+// Enabled reports whether OpenID Connect login is configured
+func (o *oidcClient) Enabled() bool {
+	return GetEnv("OIDC_ISSUER_URL", "") != "" && GetEnv("OIDC_CLIENT_ID", "") != ""
+}
+
+func (o *oidcClient) GetProviderName() string {
+	return GetEnv("OIDC_PROVIDER_NAME", "SSO")
+}
+
+// GetMapping returns the claim to role and tenant mapping from the environment
+func (o *oidcClient) GetMapping() *IdentityMapping {
+	return &IdentityMapping{
+		Roles:       ParseMappingRules(GetEnv("OIDC_ROLE_MAPPING", "")),
+		Tenants:     ParseMappingRules(GetEnv("OIDC_TENANT_MAPPING", "")),
+		DefaultRole: schema.Role(GetEnv("OIDC_DEFAULT_ROLE", "")),
+	}
+}
+
+// BeginLogin builds the authorization URL for the authorization code flow with PKCE
+func (o *oidcClient) BeginLogin(ctx context.Context, redirectURL string) (*OIDCLoginRequest, error) {
+	config, _, err := o.getConfig(ctx, redirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	return &OIDCLoginRequest{
+		URL:      config.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:    state,
+		Nonce:    nonce,
+		Verifier: verifier,
+	}, nil
+}
+
+// Exchange redeems an authorization code and verifies the returned ID token
+func (o *oidcClient) Exchange(ctx context.Context, redirectURL, code, verifier, nonce string) (*ExternalIdentity, error) {
+	config, provider, err := o.getConfig(ctx, redirectURL)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("no id_token in token response")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid id_token nonce")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Subject: idToken.Subject,
+		Groups:  claimStrings(claims[GetEnv("OIDC_GROUPS_CLAIM", "groups")]),
+	}
+
+	// Unverified email addresses must not be used for role mapping
+	if email, ok := claims["email"].(string); ok {
+		if verified, ok := claims["email_verified"].(bool); !ok || verified {
+			identity.Email = email
+		}
+	}
+
+	identity.Username, _ = claims[GetEnv("OIDC_USERNAME_CLAIM", "preferred_username")].(string)
+	if identity.Username == "" {
+		identity.Username = identity.Email
+	}
+	if identity.Username == "" {
+		identity.Username = identity.Subject
+	}
+
+	return identity, nil
+}
+
+// getConfig discovers the provider on first use, so the web UI can start while the issuer is unreachable
+func (o *oidcClient) getConfig(ctx context.Context, redirectURL string) (*oauth2.Config, *oidc.Provider, error) {
+	if !o.Enabled() {
+		return nil, nil, errors.New("OIDC login is not configured")
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.provider == nil {
+		provider, err := oidc.NewProvider(ctx, GetEnv("OIDC_ISSUER_URL", ""))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+		}
+		o.provider = provider
+	}
+
+	config := &oauth2.Config{
+		ClientID:     GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  GetEnv("OIDC_REDIRECT_URL", redirectURL),
+		Endpoint:     o.provider.Endpoint(),
+		Scopes:       strings.Fields(GetEnv("OIDC_SCOPES", "openid profile email")),
+	}
+
+	return config, o.provider, nil
+}
+
+// claimStrings converts a string or string array claim to a slice
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
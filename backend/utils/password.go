@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+var ErrInvalidCurrentPassword = errors.New("current password is incorrect")
+
+// passwordPolicy holds the password requirements configured through the environment
+type passwordPolicy struct {
+	blocklist     map[string]bool
+	blocklistOnce sync.Once
+}
+
+var PasswordPolicy = &passwordPolicy{}
+
+// Validate checks a new password against the configured policy
+func (p *passwordPolicy) Validate(password, username string) error {
+	minLength := getPolicyInt("PASSWORD_MIN_LENGTH", 8)
+	if len([]rune(password)) < minLength {
+		return fmt.Errorf("%w: must be at least %d characters long", ErrWeakPassword, minLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	if getPolicyBool("PASSWORD_REQUIRE_UPPERCASE") && !hasUpper {
+		return fmt.Errorf("%w: must contain an uppercase letter", ErrWeakPassword)
+	}
+	if getPolicyBool("PASSWORD_REQUIRE_LOWERCASE") && !hasLower {
+		return fmt.Errorf("%w: must contain a lowercase letter", ErrWeakPassword)
+	}
+	if getPolicyBool("PASSWORD_REQUIRE_DIGIT") && !hasDigit {
+		return fmt.Errorf("%w: must contain a digit", ErrWeakPassword)
+	}
+	if getPolicyBool("PASSWORD_REQUIRE_SYMBOL") && !hasSymbol {
+		return fmt.Errorf("%w: must contain a symbol", ErrWeakPassword)
+	}
+
+	if username != "" && strings.EqualFold(password, username) {
+		return fmt.Errorf("%w: must not match the username", ErrWeakPassword)
+	}
+
+	if p.isBlocked(password) {
+		return fmt.Errorf("%w: password is too common or has appeared in a data breach", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+// isBlocked checks the password against PASSWORD_BLOCKLIST_FILE, which lists one password per line
+func (p *passwordPolicy) isBlocked(password string) bool {
+	p.blocklistOnce.Do(func() {
+		p.blocklist = make(map[string]bool)
+
+		path := GetEnv("PASSWORD_BLOCKLIST_FILE", "")
+		if path == "" {
+			return
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			log.Printf("Failed to load password blocklist: %v", err)
+			return
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				p.blocklist[strings.ToLower(line)] = true
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			log.Printf("Failed to read password blocklist: %v", err)
+		}
+	})
+
+	return p.blocklist[strings.ToLower(password)]
+}
+
+// IsExpired reports whether the password is older than PASSWORD_MAX_AGE
+func (p *passwordPolicy) IsExpired(user *schema.User) bool {
+	maxAge, err := time.ParseDuration(GetEnv("PASSWORD_MAX_AGE", ""))
+	if err != nil || maxAge <= 0 {
+		return false
+	}
+
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+
+	return time.Since(changedAt) > maxAge
+}
+
+// RequiresPasswordChange checks if a local user must set a new password before using the API
+func (db *Database) RequiresPasswordChange(user *schema.User) bool {
+	if user.AuthSource != "" && user.AuthSource != schema.AuthSourceLocal {
+		return false
+	}
+
+	return user.MustChangePassword || PasswordPolicy.IsExpired(user)
+}
+
+// SetMustChangePassword flags a user to choose a new password on their next request
+func (db *Database) SetMustChangePassword(userID string, required bool) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	user.MustChangePassword = required
+	user.UpdatedAt = time.Now()
+
+	return db.saveUnsafe()
+}
+
+// ChangePassword replaces the password of a local user after verifying the current one
+func (db *Database) ChangePassword(userID, currentPassword, newPassword string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	if user.AuthSource != "" && user.AuthSource != schema.AuthSourceLocal {
+		return errors.New("password is managed by an external identity provider")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		return ErrInvalidCurrentPassword
+	}
+
+	if currentPassword == newPassword {
+		return fmt.Errorf("%w: must differ from the current password", ErrWeakPassword)
+	}
+
+	if err := PasswordPolicy.Validate(newPassword, user.Username); err != nil {
+		return err
+	}
+
+	if err := setPasswordUnsafe(user, newPassword); err != nil {
+		return err
+	}
+	user.MustChangePassword = false
+
+	return db.saveUnsafe()
+}
+
+// setPasswordUnsafe hashes and stores a new password on the user
+func setPasswordUnsafe(user *schema.User, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.PasswordHash = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.UpdatedAt = now
+	return nil
+}
+
+func getPolicyInt(key string, defaultValue int) int {
+	value, err := strconv.Atoi(GetEnv(key, ""))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+func getPolicyBool(key string) bool {
+	value, _ := strconv.ParseBool(GetEnv(key, "false"))
+	return value
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+// Password reset operations
+
+// CreatePasswordReset issues a new reset token for a local user, replacing any earlier one
+func (db *Database) CreatePasswordReset(userID string) (*schema.PasswordResetResponse, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+	if user.AuthSource != "" && user.AuthSource != schema.AuthSourceLocal {
+		return nil, errors.New("password is managed by an external identity provider")
+	}
+
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	for id, reset := range db.PasswordResets {
+		if reset.UserID == userID {
+			delete(db.PasswordResets, id)
+		}
+	}
+
+	now := time.Now()
+	reset := &schema.PasswordReset{
+		ID:        GenerateID(),
+		UserID:    userID,
+		TokenHash: HashToken(token),
+		ExpiresAt: now.Add(getLoginDuration("PASSWORD_RESET_TTL", time.Hour)),
+		CreatedAt: now,
+	}
+	db.PasswordResets[reset.ID] = reset
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return &schema.PasswordResetResponse{
+		Token:     token,
+		ResetURL:  GetPasswordResetURL(token),
+		ExpiresAt: reset.ExpiresAt,
+	}, nil
+}
+
+// ConsumePasswordReset sets a new password using a reset token. The token is
+// single-use, and the user's lockout and sessions are cleared on success.
+func (db *Database) ConsumePasswordReset(token, newPassword string) (*schema.User, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if token == "" {
+		return nil, ErrInvalidResetToken
+	}
+
+	tokenHash := HashToken(token)
+	var reset *schema.PasswordReset
+	for _, r := range db.PasswordResets {
+		if r.TokenHash == tokenHash {
+			reset = r
+			break
+		}
+	}
+	if reset == nil || time.Now().After(reset.ExpiresAt) {
+		return nil, ErrInvalidResetToken
+	}
+
+	user, exists := db.Users[reset.UserID]
+	if !exists || !user.Enabled {
+		return nil, ErrInvalidResetToken
+	}
+
+	// Keep the token usable when the new password is rejected
+	if err := PasswordPolicy.Validate(newPassword, user.Username); err != nil {
+		return nil, err
+	}
+
+	if err := setPasswordUnsafe(user, newPassword); err != nil {
+		return nil, err
+	}
+	user.MustChangePassword = false
+	user.FailedLoginAttempts = 0
+	user.LastFailedLogin = nil
+	user.LockedUntil = nil
+
+	delete(db.PasswordResets, reset.ID)
+	db.deleteUserSessionsUnsafe(user.ID)
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// FindUserForReset looks up a user by username or email address
+func (db *Database) FindUserForReset(identifier string) (*schema.User, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	for _, user := range db.Users {
+		if user.Username == identifier || (user.Email != "" && strings.EqualFold(user.Email, identifier)) {
+			return user, nil
+		}
+	}
+
+	return nil, errors.New("user not found")
+}
+
+// GetPasswordResetURL builds the link sent to users from PASSWORD_RESET_URL
+func GetPasswordResetURL(token string) string {
+	return buildTokenURL(GetEnv("PASSWORD_RESET_URL", ""), token)
+}
+
+// buildTokenURL appends a token to a page of the UI as "?token=..."
+func buildTokenURL(baseURL, token string) string {
+	if baseURL == "" {
+		return ""
+	}
+
+	tokenURL, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+
+	query := tokenURL.Query()
+	query.Set("token", token)
+	tokenURL.RawQuery = query.Encode()
+	return tokenURL.String()
+}
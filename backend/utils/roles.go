@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrRoleNotFound = errors.New("role not found")
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Role operations
+
+// ListRoles returns the built-in roles followed by the custom roles
+func (db *Database) ListRoles() []schema.RoleDefinition {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	roles := make([]schema.RoleDefinition, 0, len(schema.BuiltinRoles)+len(db.Roles))
+	for _, role := range schema.BuiltinRoles {
+		roles = append(roles, builtinRoleDefinition(role))
+	}
+
+	custom := make([]schema.RoleDefinition, 0, len(db.Roles))
+	for _, role := range db.Roles {
+		custom = append(custom, *role)
+	}
+	sort.Slice(custom, func(i, j int) bool {
+		return custom[i].Name < custom[j].Name
+	})
+
+	return append(roles, custom...)
+}
+
+// GetRole returns a built-in or custom role by name
+func (db *Database) GetRole(name schema.Role) (*schema.RoleDefinition, error) {
+	if name.IsBuiltin() {
+		role := builtinRoleDefinition(name)
+		return &role, nil
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	role, exists := db.Roles[string(name)]
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+
+	result := *role
+	return &result, nil
+}
+
+// CreateRole defines a custom role
+func (db *Database) CreateRole(req *schema.CreateRoleRequest) (*schema.RoleDefinition, error) {
+	name := schema.Role(strings.TrimSpace(string(req.Name)))
+	if !roleNamePattern.MatchString(string(name)) {
+		return nil, errors.New("role name may only contain lowercase letters, digits, '-' and '_'")
+	}
+
+	permissions, err := validateRolePermissions(req.Permissions)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if name.IsBuiltin() || db.Roles[string(name)] != nil {
+		return nil, errors.New("role already exists")
+	}
+
+	now := time.Now()
+	role := &schema.RoleDefinition{
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+		Permissions: permissions,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	db.Roles[string(name)] = role
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *role
+	return &result, nil
+}
+
+// UpdateRole changes the description or permissions of a custom role
+func (db *Database) UpdateRole(name schema.Role, req *schema.UpdateRoleRequest) (*schema.RoleDefinition, error) {
+	if name.IsBuiltin() {
+		return nil, errors.New("built-in roles are read-only")
+	}
+
+	var permissions []schema.Permission
+	if req.Permissions != nil {
+		var err error
+		if permissions, err = validateRolePermissions(req.Permissions); err != nil {
+			return nil, err
+		}
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	role, exists := db.Roles[string(name)]
+	if !exists {
+		return nil, ErrRoleNotFound
+	}
+
+	if req.Description != nil {
+		role.Description = strings.TrimSpace(*req.Description)
+	}
+	if permissions != nil {
+		role.Permissions = permissions
+	}
+	role.UpdatedAt = time.Now()
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	result := *role
+	return &result, nil
+}
+
+// DeleteRole removes a custom role that is no longer referenced
+func (db *Database) DeleteRole(name schema.Role) error {
+	if name.IsBuiltin() {
+		return errors.New("built-in roles are read-only")
+	}
+
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.Roles[string(name)]; !exists {
+		return ErrRoleNotFound
+	}
+
+	assigned := 0
+	for _, user := range db.Users {
+		if user.Role == name || (user.Elevation.IsActive() && user.Elevation.Role == name) {
+			assigned++
+		}
+	}
+	if assigned > 0 {
+		return fmt.Errorf("role is assigned to %d user(s)", assigned)
+	}
+
+	for _, group := range db.Groups {
+		for _, role := range group.Roles {
+			if role == name {
+				return fmt.Errorf("role is assigned to group %s", group.Name)
+			}
+		}
+	}
+
+	for _, elevation := range db.Elevations {
+		if elevation.Role == name && elevation.Status == schema.ElevationPending {
+			return errors.New("role is requested by a pending elevation")
+		}
+	}
+
+	for _, role := range db.Settings.RequireTwoFactorRoles {
+		if role == name {
+			return errors.New("role is referenced by the two-factor settings")
+		}
+	}
+
+	delete(db.Roles, string(name))
+	return db.saveUnsafe()
+}
+
+// RoleExists checks if a role is built in or defined as a custom role
+func (db *Database) RoleExists(role schema.Role) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.roleExistsUnsafe(role)
+}
+
+func (db *Database) roleExistsUnsafe(role schema.Role) bool {
+	return role.IsBuiltin() || db.Roles[string(role)] != nil
+}
+
+// GetUserPermissions returns the permissions a user currently holds through their own role,
+// an active elevation and the roles of their groups
+func (db *Database) GetUserPermissions(user *schema.User) []schema.Permission {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.getUserPermissionsUnsafe(user)
+}
+
+// HasPermission checks if a user has a specific permission
+func (db *Database) HasPermission(user *schema.User, permission schema.Permission) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.hasPermissionUnsafe(user, permission)
+}
+
+func (db *Database) hasPermissionUnsafe(user *schema.User, permission schema.Permission) bool {
+	for _, p := range db.getUserPermissionsUnsafe(user) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPermissionSources lists where a user's permission comes from: their own role,
+// an active elevation or the roles of their groups
+func (db *Database) GetPermissionSources(user *schema.User, permission schema.Permission) []string {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	sources := []string{}
+	if db.roleHasPermissionUnsafe(user.Role, permission) {
+		sources = append(sources, fmt.Sprintf("role %s", user.Role))
+	}
+	if user.Elevation.IsActive() && db.roleHasPermissionUnsafe(user.Elevation.Role, permission) {
+		sources = append(sources, fmt.Sprintf("elevation to role %s", user.Elevation.Role))
+	}
+	for _, group := range db.getUserGroupsUnsafe(user.ID) {
+		for _, role := range group.Roles {
+			if db.roleHasPermissionUnsafe(role, permission) {
+				sources = append(sources, fmt.Sprintf("group %s (role %s)", group.Name, role))
+			}
+		}
+	}
+	return sources
+}
+
+func (db *Database) roleHasPermissionUnsafe(role schema.Role, permission schema.Permission) bool {
+	for _, p := range db.getRolePermissionsUnsafe(role) {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *Database) getUserPermissionsUnsafe(user *schema.User) []schema.Permission {
+	permissions := db.getRolePermissionsUnsafe(user.Role)
+	if user.Elevation.IsActive() {
+		permissions = append(permissions, db.getRolePermissionsUnsafe(user.Elevation.Role)...)
+	}
+	for _, group := range db.Groups {
+		if !group.HasMember(user.ID) {
+			continue
+		}
+		for _, role := range group.Roles {
+			permissions = append(permissions, db.getRolePermissionsUnsafe(role)...)
+		}
+	}
+	return permissions
+}
+
+func (db *Database) getRolePermissionsUnsafe(role schema.Role) []schema.Permission {
+	if role.IsBuiltin() {
+		return schema.GetRolePermissions(role)
+	}
+	if custom, exists := db.Roles[string(role)]; exists {
+		return append([]schema.Permission{}, custom.Permissions...)
+	}
+	return []schema.Permission{}
+}
+
+func builtinRoleDefinition(role schema.Role) schema.RoleDefinition {
+	return schema.RoleDefinition{
+		Name:        role,
+		Permissions: schema.GetRolePermissions(role),
+		Builtin:     true,
+	}
+}
+
+// validateRolePermissions rejects unknown permissions and removes duplicates
+func validateRolePermissions(permissions []schema.Permission) ([]schema.Permission, error) {
+	result := make([]schema.Permission, 0, len(permissions))
+	seen := make(map[schema.Permission]bool)
+	for _, permission := range permissions {
+		if !permission.IsValid() {
+			return nil, errors.New("unknown permission: " + string(permission))
+		}
+		if !seen[permission] {
+			seen[permission] = true
+			result = append(result, permission)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,271 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"khairul169/garage-webui/schema"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var ErrSCIMConflict = errors.New("resource already exists")
+
+// scimProvisioner authenticates SCIM clients and maps provisioned groups to roles
+type scimProvisioner struct{}
+
+var SCIM = &scimProvisioner{}
+
+// Enabled reports whether the SCIM endpoint is configured
+func (s *scimProvisioner) Enabled() bool {
+	return GetEnv("SCIM_TOKEN", "") != ""
+}
+
+// Authenticate checks the dedicated SCIM bearer token of a request
+func (s *scimProvisioner) Authenticate(r *http.Request) bool {
+	expected := GetEnv("SCIM_TOKEN", "")
+	header := r.Header.Get("Authorization")
+	if expected == "" || len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return false
+	}
+
+	token := strings.TrimSpace(header[7:])
+	return subtle.ConstantTimeCompare([]byte(token), []byte(expected)) == 1
+}
+
+// GetMapping returns the group to role and tenant mapping from the environment
+func (s *scimProvisioner) GetMapping() *IdentityMapping {
+	return &IdentityMapping{
+		Roles:       ParseMappingRules(GetEnv("SCIM_ROLE_MAPPING", "")),
+		Tenants:     ParseMappingRules(GetEnv("SCIM_TENANT_MAPPING", "")),
+		DefaultRole: schema.Role(GetEnv("SCIM_DEFAULT_ROLE", string(schema.RoleReadOnly))),
+	}
+}
+
+// SCIMFilter is a parsed SCIM filter made of comparisons joined by "and"
+type SCIMFilter []SCIMFilterClause
+
+type SCIMFilterClause struct {
+	Attribute string
+	Operator  string
+	Value     string
+}
+
+// ParseSCIMFilter parses the subset of the SCIM filter syntax used by
+// identity providers, e.g. `userName eq "alice" and active eq true`
+func ParseSCIMFilter(filter string) (SCIMFilter, error) {
+	var result SCIMFilter
+	filter = strings.TrimSpace(filter)
+
+	for filter != "" {
+		fields := strings.SplitN(filter, " ", 3)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid filter: %s", filter)
+		}
+
+		clause := SCIMFilterClause{Attribute: fields[0], Operator: strings.ToLower(fields[1])}
+		rest := ""
+		if clause.Operator != "pr" {
+			if len(fields) < 3 {
+				return nil, fmt.Errorf("invalid filter: %s", filter)
+			}
+			value := strings.TrimSpace(fields[2])
+
+			if strings.HasPrefix(value, `"`) {
+				end := strings.Index(value[1:], `"`)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated string in filter: %s", filter)
+				}
+				clause.Value = value[1 : end+1]
+				rest = value[end+2:]
+			} else {
+				parts := strings.SplitN(value, " ", 2)
+				clause.Value = parts[0]
+				if len(parts) > 1 {
+					rest = parts[1]
+				}
+			}
+		} else if len(fields) == 3 {
+			rest = fields[2]
+		}
+
+		switch clause.Operator {
+		case "eq", "ne", "co", "sw", "ew", "pr":
+		default:
+			return nil, fmt.Errorf("unsupported filter operator: %s", clause.Operator)
+		}
+		result = append(result, clause)
+
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			break
+		}
+		if !strings.HasPrefix(strings.ToLower(rest), "and ") {
+			return nil, fmt.Errorf("unsupported filter expression: %s", rest)
+		}
+		filter = strings.TrimSpace(rest[4:])
+	}
+
+	return result, nil
+}
+
+// Match evaluates the filter against the attribute values returned by get.
+// Attribute names and string comparisons are case-insensitive.
+func (f SCIMFilter) Match(get func(attribute string) []string) bool {
+	for _, clause := range f {
+		values := get(strings.ToLower(clause.Attribute))
+		if !clause.match(values) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c SCIMFilterClause) match(values []string) bool {
+	if c.Operator == "pr" {
+		for _, value := range values {
+			if value != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	expected := strings.ToLower(c.Value)
+	matched := false
+	for _, value := range values {
+		value = strings.ToLower(value)
+		switch c.Operator {
+		case "eq", "ne":
+			matched = value == expected
+		case "co":
+			matched = strings.Contains(value, expected)
+		case "sw":
+			matched = strings.HasPrefix(value, expected)
+		case "ew":
+			matched = strings.HasSuffix(value, expected)
+		}
+		if matched {
+			break
+		}
+	}
+
+	if c.Operator == "ne" {
+		return !matched
+	}
+	return matched
+}
+
+// SCIM user operations
+
+// CreateSCIMUser creates a user provisioned by the identity provider. Without a
+// password the account can only log in through single sign-on.
+func (db *Database) CreateSCIMUser(username, email, externalID, password string, enabled bool) (*schema.User, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for _, user := range db.Users {
+		if user.Username == username {
+			return nil, ErrSCIMConflict
+		}
+	}
+
+	now := time.Now()
+	user := &schema.User{
+		ID:              GenerateID(),
+		Username:        username,
+		Email:           email,
+		Role:            SCIM.GetMapping().DefaultRole,
+		Enabled:         enabled,
+		AuthSource:      schema.AuthSourceLocal,
+		SCIMProvisioned: true,
+		SCIMExternalID:  externalID,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	if password != "" {
+		if err := PasswordPolicy.Validate(password, username); err != nil {
+			return nil, err
+		}
+		if err := setPasswordUnsafe(user, password); err != nil {
+			return nil, err
+		}
+	}
+
+	db.Users[user.ID] = user
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// SetSCIMExternalID stores the identifier the identity provider uses for a SCIM provisioned user
+func (db *Database) SetSCIMExternalID(userID, externalID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists || !user.SCIMProvisioned {
+		return errors.New("user not found")
+	}
+
+	user.SCIMExternalID = externalID
+	user.UpdatedAt = time.Now()
+	return db.saveUnsafe()
+}
+
+// SyncSCIMUsers recomputes the role and tenant of SCIM provisioned users from their groups
+func (db *Database) SyncSCIMUsers(userIDs ...string) error {
+	mapping := SCIM.GetMapping()
+
+	for _, userID := range userIDs {
+		user, err := db.GetUser(userID)
+		if err != nil || !user.SCIMProvisioned {
+			continue
+		}
+
+		groups := db.GetUserGroups(userID)
+		names := make([]string, 0, len(groups))
+		for _, group := range groups {
+			names = append(names, group.Name)
+		}
+
+		role, tenantID, err := mapping.Resolve(names, user.Email)
+		if err != nil {
+			log.Printf("Cannot map SCIM groups of %s: %v", user.Username, err)
+			continue
+		}
+
+		db.mutex.Lock()
+		if user, exists := db.Users[userID]; exists {
+			user.Role = role
+			user.TenantID = tenantID
+			user.UpdatedAt = time.Now()
+		}
+		err = db.saveUnsafe()
+		db.mutex.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   SCIMFilter
+	}{
+		{``, nil},
+		{`userName eq "alice"`, SCIMFilter{{Attribute: "userName", Operator: "eq", Value: "alice"}}},
+		{`userName EQ "alice smith"`, SCIMFilter{{Attribute: "userName", Operator: "eq", Value: "alice smith"}}},
+		{`active eq true`, SCIMFilter{{Attribute: "active", Operator: "eq", Value: "true"}}},
+		{`externalId pr`, SCIMFilter{{Attribute: "externalId", Operator: "pr"}}},
+		{`userName sw "al" and active eq true`, SCIMFilter{
+			{Attribute: "userName", Operator: "sw", Value: "al"},
+			{Attribute: "active", Operator: "eq", Value: "true"},
+		}},
+		{`externalId pr and emails.value ew "@example.com"`, SCIMFilter{
+			{Attribute: "externalId", Operator: "pr"},
+			{Attribute: "emails.value", Operator: "ew", Value: "@example.com"},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSCIMFilter(tt.filter)
+		if err != nil {
+			t.Errorf("ParseSCIMFilter(%q) error = %v", tt.filter, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSCIMFilter(%q) = %+v, want %+v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestParseSCIMFilterErrors(t *testing.T) {
+	filters := []string{
+		`userName`,
+		`userName eq`,
+		`userName eq "alice`,
+		`userName gt "alice"`,
+		`userName eq "alice" or userName eq "bob"`,
+		`userName eq "alice" and`,
+	}
+
+	for _, filter := range filters {
+		if _, err := ParseSCIMFilter(filter); err == nil {
+			t.Errorf("ParseSCIMFilter(%q) returned no error", filter)
+		}
+	}
+}
+
+func TestSCIMFilterClauseMatch(t *testing.T) {
+	tests := []struct {
+		clause SCIMFilterClause
+		values []string
+		want   bool
+	}{
+		{SCIMFilterClause{Operator: "eq", Value: "Alice"}, []string{"alice"}, true},
+		{SCIMFilterClause{Operator: "eq", Value: "alice"}, []string{"bob"}, false},
+		{SCIMFilterClause{Operator: "eq", Value: "alice"}, []string{"bob", "alice"}, true},
+		{SCIMFilterClause{Operator: "ne", Value: "alice"}, []string{"bob"}, true},
+		{SCIMFilterClause{Operator: "ne", Value: "alice"}, []string{"alice"}, false},
+		{SCIMFilterClause{Operator: "co", Value: "LIC"}, []string{"alice"}, true},
+		{SCIMFilterClause{Operator: "sw", Value: "al"}, []string{"alice"}, true},
+		{SCIMFilterClause{Operator: "sw", Value: "ice"}, []string{"alice"}, false},
+		{SCIMFilterClause{Operator: "ew", Value: "@example.com"}, []string{"a@example.com"}, true},
+		{SCIMFilterClause{Operator: "pr"}, []string{"", "x"}, true},
+		{SCIMFilterClause{Operator: "pr"}, []string{""}, false},
+		{SCIMFilterClause{Operator: "pr"}, nil, false},
+		{SCIMFilterClause{Operator: "eq", Value: "alice"}, nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.clause.match(tt.values); got != tt.want {
+			t.Errorf("%+v.match(%q) = %v, want %v", tt.clause, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestSCIMFilterMatch(t *testing.T) {
+	filter, err := ParseSCIMFilter(`userName eq "alice" and active eq true`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attributes := map[string][]string{"username": {"Alice"}, "active": {"true"}}
+	if !filter.Match(func(attribute string) []string { return attributes[attribute] }) {
+		t.Error("filter does not match an active alice")
+	}
+
+	attributes["active"] = []string{"false"}
+	if filter.Match(func(attribute string) []string { return attributes[attribute] }) {
+		t.Error("filter matches an inactive alice")
+	}
+}
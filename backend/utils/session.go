@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"khairul169/garage-webui/schema"
 	"net/http"
+	"path/filepath"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
@@ -13,11 +17,52 @@ type SessionManager struct {
 
 var Session *SessionManager
 
-func InitSessionManager() *scs.SessionManager {
+// SessionLifetime is the lifetime of both cookie sessions and session records
+const SessionLifetime = 24 * time.Hour
+
+// sessionTouchInterval limits how often session activity is written to disk
+const sessionTouchInterval = time.Minute
+
+// AuthMethod describes how a request was authenticated
+type AuthMethod string
+
+const (
+	AuthMethodSession AuthMethod = "session"
+	AuthMethodBearer  AuthMethod = "bearer"
+	AuthMethodToken   AuthMethod = "access_token"
+	AuthMethodProxy   AuthMethod = "proxy"
+)
+
+// AuthInfo holds the identity resolved by the auth middleware for a request
+type AuthInfo struct {
+	UserID    string
+	SessionID string
+	Method    AuthMethod
+	// Scopes restricts the user's permissions when authenticated with an access token
+	Scopes []schema.Permission
+	// ImpersonatorID is the admin behind the session while UserID is being impersonated
+	ImpersonatorID string
+	// User is the user resolved once per request by the auth middleware
+	User *schema.User
+}
+
+type authContextKey struct{}
+
+func InitSessionManager() (*scs.SessionManager, error) {
+	store, err := NewFileStore(filepath.Join(GetEnv("DATA_DIR", "./data"), "sessions.json"))
+	if err != nil {
+		return nil, err
+	}
+	store.Cleanup(getCleanupInterval())
+
 	sessMgr := scs.New()
-	sessMgr.Lifetime = 24 * time.Hour
+	sessMgr.Lifetime = SessionLifetime
+	sessMgr.Cookie.HttpOnly = true
+	sessMgr.Cookie.Secure = GetCookieSecure()
+	sessMgr.Cookie.SameSite = GetCookieSameSite()
+	sessMgr.Store = store
 	Session = &SessionManager{mgr: sessMgr}
-	return sessMgr
+	return sessMgr, nil
 }
 
 func (s *SessionManager) Get(r *http.Request, key string) interface{} {
@@ -31,3 +76,75 @@ func (s *SessionManager) Set(r *http.Request, key string, value interface{}) {
 func (s *SessionManager) Clear(r *http.Request) error {
 	return s.mgr.Clear(r.Context())
 }
+
+// Destroy deletes the session data from the store and expires the cookie
+func (s *SessionManager) Destroy(r *http.Request) error {
+	return s.mgr.Destroy(r.Context())
+}
+
+// RenewToken issues a new session token, which should be done on login to prevent session fixation
+func (s *SessionManager) RenewToken(r *http.Request) error {
+	return s.mgr.RenewToken(r.Context())
+}
+
+// WithAuth returns a shallow copy of the request carrying the auth info
+func WithAuth(r *http.Request, info *AuthInfo) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), authContextKey{}, info))
+}
+
+// GetAuth returns the auth info attached by the auth middleware, or nil
+func GetAuth(r *http.Request) *AuthInfo {
+	info, _ := r.Context().Value(authContextKey{}).(*AuthInfo)
+	return info
+}
+
+// AllowsScope checks if the credential used for the request grants a permission.
+// Sessions are unrestricted; access tokens are limited to their scopes.
+func (a *AuthInfo) AllowsScope(permission schema.Permission) bool {
+	if a == nil || a.Method != AuthMethodToken {
+		return true
+	}
+
+	for _, p := range a.Scopes {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// GetAuthUserID returns the ID of the authenticated user for the request,
+// falling back to the cookie session when the auth middleware did not run
+func GetAuthUserID(r *http.Request) string {
+	if info := GetAuth(r); info != nil {
+		return info.UserID
+	}
+
+	if userID, ok := Session.Get(r, "user_id").(string); ok {
+		return userID
+	}
+
+	return ""
+}
+
+// GetAuthUser returns the authenticated user for the request
+func GetAuthUser(r *http.Request) (*schema.User, error) {
+	if info := GetAuth(r); info != nil && info.User != nil {
+		return info.User, nil
+	}
+
+	userID := GetAuthUserID(r)
+	if userID == "" {
+		return nil, errors.New("not authenticated")
+	}
+	return DB.GetUser(userID)
+}
+
+// getCleanupInterval returns how often expired sessions are purged
+func getCleanupInterval() time.Duration {
+	interval, err := time.ParseDuration(GetEnv("SESSION_CLEANUP_INTERVAL", "1h"))
+	if err != nil || interval <= 0 {
+		return time.Hour
+	}
+	return interval
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is a scs session store persisted to a JSON file, so cookie
+// sessions survive restarts. Tokens are stored hashed.
+type FileStore struct {
+	path  string
+	items map[string]fileStoreItem
+	mutex sync.Mutex
+}
+
+type fileStoreItem struct {
+	Data   []byte    `json:"data"`
+	Expiry time.Time `json:"expiry"`
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{
+		path:  path,
+		items: make(map[string]fileStoreItem),
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &store.items); err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+func (s *FileStore) Find(token string) ([]byte, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	item, exists := s.items[HashToken(token)]
+	if !exists || time.Now().After(item.Expiry) {
+		return nil, false, nil
+	}
+
+	return item.Data, true, nil
+}
+
+func (s *FileStore) Commit(token string, b []byte, expiry time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.items[HashToken(token)] = fileStoreItem{Data: b, Expiry: expiry}
+	return s.saveUnsafe()
+}
+
+func (s *FileStore) Delete(token string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := HashToken(token)
+	if _, exists := s.items[key]; !exists {
+		return nil
+	}
+
+	delete(s.items, key)
+	return s.saveUnsafe()
+}
+
+// Cleanup periodically removes expired sessions from the store
+func (s *FileStore) Cleanup(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			s.mutex.Lock()
+			now := time.Now()
+			for key, item := range s.items {
+				if now.After(item.Expiry) {
+					delete(s.items, key)
+				}
+			}
+			s.saveUnsafe()
+			s.mutex.Unlock()
+		}
+	}()
+}
+
+func (s *FileStore) saveUnsafe() error {
+	data, err := json.Marshal(s.items)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0600)
+}
@@ -0,0 +1,234 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"time"
+)
+
+var ErrOutsideTenant = errors.New("resource belongs to another tenant")
+
+var ErrRoleNotGrantable = errors.New("cannot assign a role with permissions you do not have")
+
+// Tenant scoping
+
+// GetUserScope returns the tenant a user is limited to. Users with the system_admin
+// permission are not scoped and see the whole cluster; scoped users without a tenant
+// see the users and resources that do not belong to any tenant.
+func (db *Database) GetUserScope(user *schema.User) (tenantID string, scoped bool) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.getUserScopeUnsafe(user)
+}
+
+func (db *Database) getUserScopeUnsafe(user *schema.User) (string, bool) {
+	if db.hasPermissionUnsafe(user, schema.PermissionSystemAdmin) {
+		return "", false
+	}
+	return tenantIDValue(db.getUserTenantIDUnsafe(user)), true
+}
+
+// CanAccessUser checks if an actor may see or manage another user
+func (db *Database) CanAccessUser(actor *schema.User, target *schema.User) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tenantID, scoped := db.getUserScopeUnsafe(actor)
+	return !scoped || tenantIDValue(db.getUserTenantIDUnsafe(target)) == tenantID
+}
+
+// CanManageUser checks if an actor may change another user. Besides the tenant
+// check of CanAccessUser, scoped actors cannot manage users holding permissions
+// they lack themselves through their role, groups or an active elevation.
+func (db *Database) CanManageUser(actor *schema.User, target *schema.User) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tenantID, scoped := db.getUserScopeUnsafe(actor)
+	if !scoped {
+		return true
+	}
+	if tenantIDValue(db.getUserTenantIDUnsafe(target)) != tenantID {
+		return false
+	}
+	return db.holdsPermissionsUnsafe(actor, db.getUserPermissionsUnsafe(target))
+}
+
+// FilterUsers returns the users visible to an actor
+func (db *Database) FilterUsers(actor *schema.User, users []*schema.User) []*schema.User {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tenantID, scoped := db.getUserScopeUnsafe(actor)
+	if !scoped {
+		return users
+	}
+
+	result := make([]*schema.User, 0, len(users))
+	for _, user := range users {
+		if tenantIDValue(db.getUserTenantIDUnsafe(user)) == tenantID {
+			result = append(result, user)
+		}
+	}
+	return result
+}
+
+// CanAccessGroup checks if a group is bound to the tenant a user is scoped to
+func (db *Database) CanAccessGroup(actor *schema.User, group *schema.Group) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tenantID, scoped := db.getUserScopeUnsafe(actor)
+	return !scoped || tenantIDValue(group.TenantID) == tenantID
+}
+
+// FilterGroups returns the groups visible to an actor, listing only the members
+// the actor can see
+func (db *Database) FilterGroups(actor *schema.User, groups []*schema.Group) []*schema.Group {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tenantID, scoped := db.getUserScopeUnsafe(actor)
+	if !scoped {
+		return groups
+	}
+
+	result := make([]*schema.Group, 0, len(groups))
+	for _, group := range groups {
+		if tenantIDValue(group.TenantID) != tenantID {
+			continue
+		}
+		result = append(result, db.scopeGroupMembersUnsafe(group, tenantID))
+	}
+	return result
+}
+
+// ScopeGroupMembers returns a copy of a group listing only the members visible to an actor
+func (db *Database) ScopeGroupMembers(actor *schema.User, group *schema.Group) *schema.Group {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	tenantID, scoped := db.getUserScopeUnsafe(actor)
+	if !scoped {
+		return group
+	}
+	return db.scopeGroupMembersUnsafe(group, tenantID)
+}
+
+func (db *Database) scopeGroupMembersUnsafe(group *schema.Group, tenantID string) *schema.Group {
+	result := *group
+	result.Members = make([]string, 0, len(group.Members))
+	for _, userID := range group.Members {
+		if user, exists := db.Users[userID]; exists && tenantIDValue(db.getUserTenantIDUnsafe(user)) == tenantID {
+			result.Members = append(result.Members, userID)
+		}
+	}
+	return &result
+}
+
+// CheckUserAssignment validates the role and tenant an actor gives to a user. Scoped
+// actors may only place users in their own tenant and only grant roles whose
+// permissions they hold themselves, which rules out promoting anyone to admin.
+func (db *Database) CheckUserAssignment(actor *schema.User, role *schema.Role, tenantID *string) error {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	actorTenantID, scoped := db.getUserScopeUnsafe(actor)
+	if !scoped {
+		return nil
+	}
+
+	if tenantID != nil && *tenantID != actorTenantID {
+		return ErrOutsideTenant
+	}
+
+	if role != nil && !db.holdsPermissionsUnsafe(actor, db.getRolePermissionsUnsafe(*role)) {
+		return ErrRoleNotGrantable
+	}
+	return nil
+}
+
+// holdsPermissionsUnsafe checks if a user has every permission in a list
+func (db *Database) holdsPermissionsUnsafe(user *schema.User, permissions []schema.Permission) bool {
+	granted := make(map[schema.Permission]bool)
+	for _, permission := range db.getUserPermissionsUnsafe(user) {
+		granted[permission] = true
+	}
+	for _, permission := range permissions {
+		if !granted[permission] {
+			return false
+		}
+	}
+	return true
+}
+
+// CanAccessBucket checks if a bucket belongs to the tenant a user is scoped to
+func (db *Database) CanAccessBucket(user *schema.User, bucketID string) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.canAccessResourceUnsafe(user, db.BucketOwners, bucketID)
+}
+
+// CanAccessKey checks if an access key belongs to the tenant a user is scoped to
+func (db *Database) CanAccessKey(user *schema.User, accessKeyID string) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.canAccessResourceUnsafe(user, db.KeyOwners, accessKeyID)
+}
+
+func (db *Database) canAccessResourceUnsafe(user *schema.User, owners map[string]*schema.ResourceOwner, id string) bool {
+	tenantID, scoped := db.getUserScopeUnsafe(user)
+	if !scoped {
+		return true
+	}
+
+	owner, exists := owners[id]
+	if !exists {
+		return tenantID == ""
+	}
+	return owner.TenantID == tenantID
+}
+
+// SetBucketTenant assigns a bucket to a tenant, or returns it to the shared pool
+func (db *Database) SetBucketTenant(bucketID, tenantID, assignedBy string) error {
+	return db.setResourceTenant(db.BucketOwners, bucketID, tenantID, assignedBy)
+}
+
+// SetKeyTenant assigns an access key to a tenant, or returns it to the shared pool
+func (db *Database) SetKeyTenant(accessKeyID, tenantID, assignedBy string) error {
+	return db.setResourceTenant(db.KeyOwners, accessKeyID, tenantID, assignedBy)
+}
+
+func (db *Database) setResourceTenant(owners map[string]*schema.ResourceOwner, id, tenantID, assignedBy string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if tenantID == "" {
+		if _, exists := owners[id]; !exists {
+			return nil
+		}
+		delete(owners, id)
+		return db.saveUnsafe()
+	}
+
+	if _, exists := db.Tenants[tenantID]; !exists {
+		return errors.New("tenant not found")
+	}
+
+	owners[id] = &schema.ResourceOwner{
+		TenantID:   tenantID,
+		AssignedBy: assignedBy,
+		AssignedAt: time.Now(),
+	}
+	return db.saveUnsafe()
+}
+
+func tenantIDValue(tenantID *string) string {
+	if tenantID == nil {
+		return ""
+	}
+	return *tenantID
+}
@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"khairul169/garage-webui/schema"
+	"testing"
+	"time"
+)
+
+func newScopeTestDatabase(users ...*schema.User) *Database {
+	db := &Database{
+		Users:   make(map[string]*schema.User),
+		Tenants: map[string]*schema.Tenant{"acme": {ID: "acme"}},
+		Groups:  make(map[string]*schema.Group),
+		Roles:   make(map[string]*schema.RoleDefinition),
+	}
+	for _, user := range users {
+		db.Users[user.ID] = user
+	}
+	return db
+}
+
+func TestCanManageUserWithoutTenant(t *testing.T) {
+	actor := &schema.User{ID: "actor", Role: schema.RoleTenantAdmin}
+	admin := &schema.User{ID: "admin", Role: schema.RoleAdmin}
+	member := &schema.User{ID: "member", Role: schema.RoleUser}
+	elevated := &schema.User{ID: "elevated", Role: schema.RoleUser, Elevation: &schema.Elevation{
+		Role:      schema.RoleAdmin,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}}
+	grouped := &schema.User{ID: "grouped", Role: schema.RoleUser}
+
+	db := newScopeTestDatabase(actor, admin, member, elevated, grouped)
+	db.Groups["ops"] = &schema.Group{ID: "ops", Members: []string{"grouped"}, Roles: []schema.Role{schema.RoleAdmin}}
+
+	if db.CanManageUser(actor, admin) {
+		t.Error("tenant admin without a tenant can manage the global admin")
+	}
+	if db.CanManageUser(actor, elevated) {
+		t.Error("tenant admin can manage a user with an active admin elevation")
+	}
+	if db.CanManageUser(actor, grouped) {
+		t.Error("tenant admin can manage a user with an admin group role")
+	}
+	if !db.CanManageUser(actor, member) {
+		t.Error("tenant admin cannot manage a user in its scope")
+	}
+	if !db.CanManageUser(admin, actor) {
+		t.Error("admin cannot manage a tenant admin")
+	}
+}
+
+func TestCanManageUserWithTenant(t *testing.T) {
+	tenantID := "acme"
+	actor := &schema.User{ID: "actor", Role: schema.RoleTenantAdmin, TenantID: &tenantID}
+	admin := &schema.User{ID: "admin", Role: schema.RoleAdmin, TenantID: &tenantID}
+	member := &schema.User{ID: "member", Role: schema.RoleUser, TenantID: &tenantID}
+	outsider := &schema.User{ID: "outsider", Role: schema.RoleUser}
+
+	db := newScopeTestDatabase(actor, admin, member, outsider)
+
+	if db.CanManageUser(actor, admin) {
+		t.Error("tenant admin can manage an admin placed in its tenant")
+	}
+	if db.CanManageUser(actor, outsider) {
+		t.Error("tenant admin can manage a user outside its tenant")
+	}
+	if !db.CanManageUser(actor, member) {
+		t.Error("tenant admin cannot manage a user in its tenant")
+	}
+	if !db.CanManageUser(actor, actor) {
+		t.Error("tenant admin cannot manage itself")
+	}
+}
+
+func TestFilterGroups(t *testing.T) {
+	acme, globex := "acme", "globex"
+	actor := &schema.User{ID: "actor", Role: schema.RoleTenantAdmin, TenantID: &acme}
+	member := &schema.User{ID: "member", Role: schema.RoleUser, TenantID: &acme}
+	outsider := &schema.User{ID: "outsider", Role: schema.RoleUser, TenantID: &globex}
+	admin := &schema.User{ID: "admin", Role: schema.RoleAdmin}
+
+	db := newScopeTestDatabase(actor, member, outsider, admin)
+	groups := []*schema.Group{
+		{ID: "acme-ops", TenantID: &acme, Members: []string{"member"}},
+		{ID: "globex-ops", TenantID: &globex, Members: []string{"outsider"}},
+		{ID: "shared", Members: []string{"member", "outsider"}},
+	}
+
+	visible := db.FilterGroups(actor, groups)
+	if len(visible) != 1 || visible[0].ID != "acme-ops" {
+		t.Fatalf("tenant admin sees groups %+v, want only acme-ops", visible)
+	}
+	if db.CanAccessGroup(actor, groups[1]) {
+		t.Error("tenant admin can access a group of another tenant")
+	}
+	if len(db.FilterGroups(admin, groups)) != len(groups) {
+		t.Error("admin does not see every group")
+	}
+
+	scoped := db.ScopeGroupMembers(actor, groups[2])
+	if len(scoped.Members) != 1 || scoped.Members[0] != "member" {
+		t.Errorf("tenant admin sees members %v, want [member]", scoped.Members)
+	}
+	if len(groups[2].Members) != 2 {
+		t.Error("ScopeGroupMembers changed the original group")
+	}
+}
@@ -0,0 +1,206 @@
+package utils
+
+import (
+	"errors"
+	"khairul169/garage-webui/schema"
+	"strings"
+	"time"
+)
+
+const recoveryCodeCount = 10
+
+var ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+
+// Two-factor operations
+
+// BeginTwoFactorSetup generates a new pending TOTP secret for the user
+func (db *Database) BeginTwoFactorSetup(userID string) (string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.Users[userID]; !exists {
+		return "", errors.New("user not found")
+	}
+
+	config := db.TwoFactor[userID]
+	if config != nil && config.Enabled {
+		return "", errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if config == nil {
+		config = &schema.TwoFactor{UserID: userID}
+		db.TwoFactor[userID] = config
+	}
+	config.PendingSecret = secret
+
+	if err := db.saveUnsafe(); err != nil {
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// EnableTwoFactor confirms the pending secret with a code and returns new recovery codes
+func (db *Database) EnableTwoFactor(userID, code string) ([]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return nil, errors.New("user not found")
+	}
+
+	config := db.TwoFactor[userID]
+	if config == nil || config.PendingSecret == "" {
+		return nil, errors.New("two-factor setup has not been started")
+	}
+
+	step, ok := ValidateTOTP(config.PendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, err := db.resetRecoveryCodesUnsafe(config)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	config.Secret = config.PendingSecret
+	config.PendingSecret = ""
+	config.Enabled = true
+	config.LastUsedStep = step
+	config.EnabledAt = &now
+
+	user.TwoFactorEnabled = true
+	user.UpdatedAt = now
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// VerifyTwoFactor checks a TOTP code or consumes a recovery code
+func (db *Database) VerifyTwoFactor(userID, code string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	config := db.TwoFactor[userID]
+	if config == nil || !config.Enabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	// Codes cannot be reused within their validity window
+	if step, ok := ValidateTOTP(config.Secret, code, time.Now()); ok {
+		if step <= config.LastUsedStep {
+			return ErrInvalidTwoFactorCode
+		}
+		config.LastUsedStep = step
+		return db.saveUnsafe()
+	}
+
+	codeHash := HashToken(normalizeRecoveryCode(code))
+	for i, hash := range config.RecoveryCodes {
+		if hash == codeHash {
+			config.RecoveryCodes = append(config.RecoveryCodes[:i], config.RecoveryCodes[i+1:]...)
+			return db.saveUnsafe()
+		}
+	}
+
+	return ErrInvalidTwoFactorCode
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes
+func (db *Database) RegenerateRecoveryCodes(userID string) ([]string, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	config := db.TwoFactor[userID]
+	if config == nil || !config.Enabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	codes, err := db.resetRecoveryCodesUnsafe(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor removes the user's 2FA enrollment
+func (db *Database) DisableTwoFactor(userID string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	user, exists := db.Users[userID]
+	if !exists {
+		return errors.New("user not found")
+	}
+
+	delete(db.TwoFactor, userID)
+	user.TwoFactorEnabled = false
+	user.UpdatedAt = time.Now()
+
+	return db.saveUnsafe()
+}
+
+// RequiresTwoFactorSetup checks if the user must enroll in 2FA before using the API
+func (db *Database) RequiresTwoFactorSetup(user *schema.User) bool {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return !user.TwoFactorEnabled && db.Settings.RequiresTwoFactor(user.Role)
+}
+
+// Settings operations
+func (db *Database) GetSecuritySettings() schema.SecuritySettings {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	return db.Settings
+}
+
+func (db *Database) UpdateSecuritySettings(settings *schema.SecuritySettings) (*schema.SecuritySettings, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	db.Settings = *settings
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	settingsCopy := db.Settings
+	return &settingsCopy, nil
+}
+
+// resetRecoveryCodesUnsafe generates recovery codes and stores their hashes
+func (db *Database) resetRecoveryCodesUnsafe(config *schema.TwoFactor) ([]string, error) {
+	codes, err := GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	config.RecoveryCodes = make([]string, 0, len(codes))
+	for _, code := range codes {
+		config.RecoveryCodes = append(config.RecoveryCodes, HashToken(code))
+	}
+
+	return codes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
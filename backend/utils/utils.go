@@ -3,6 +3,7 @@ package utils
 import (
 	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
@@ -75,11 +76,15 @@ func ResponseErrorStatus(w http.ResponseWriter, err error, status int) {
 }
 
 func ResponseSuccess(w http.ResponseWriter, data interface{}) {
+	ResponseSuccessStatus(w, data, http.StatusOK)
+}
+
+func ResponseSuccessStatus(w http.ResponseWriter, data interface{}, status int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("X-Frame-Options", "DENY")
 	w.Header().Set("X-XSS-Protection", "1; mode=block")
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(status)
 
 	response := map[string]interface{}{
 		"success": true,
@@ -88,3 +93,74 @@ func ResponseSuccess(w http.ResponseWriter, data interface{}) {
 
 	json.NewEncoder(w).Encode(response)
 }
+
+// GetClientIP extracts the real client IP from request. The X-Forwarded-For and
+// X-Real-IP headers are only honored when the request comes from one of
+// TRUSTED_PROXIES, as any other client could forge them.
+func GetClientIP(r *http.Request) string {
+	ip := remoteHost(r.RemoteAddr)
+	if !IsTrustedProxy(r) {
+		return ip
+	}
+
+	// Check X-Forwarded-For header, skipping the trusted proxies appended at its end
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		ips := strings.Split(xff, ",")
+		for i := len(ips) - 1; i >= 0; i-- {
+			forwarded := strings.TrimSpace(ips[i])
+			if i == 0 || !isTrustedProxyAddress(forwarded) {
+				return forwarded
+			}
+		}
+	}
+
+	// Check X-Real-IP header
+	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+		return xri
+	}
+
+	return ip
+}
+
+// IsTrustedProxy checks if the request comes directly from one of TRUSTED_PROXIES.
+// The TCP peer address is used, never a forwarded header, as those can be spoofed.
+func IsTrustedProxy(r *http.Request) bool {
+	return isTrustedProxyAddress(remoteHost(r.RemoteAddr))
+}
+
+// isTrustedProxyAddress checks if an IP address is one of TRUSTED_PROXIES
+func isTrustedProxyAddress(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+
+	for _, value := range splitList(GetEnv("TRUSTED_PROXIES", "")) {
+		if !strings.Contains(value, "/") {
+			if trusted := net.ParseIP(value); trusted != nil && trusted.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			log.Printf("Invalid trusted proxy CIDR %q: %v", value, err)
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// remoteHost strips the port from the TCP peer address of a request
+func remoteHost(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetClientIP(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8")
+
+	tests := []struct {
+		remoteAddr string
+		xff        string
+		xri        string
+		want       string
+	}{
+		{"203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"203.0.113.7:5000", "198.51.100.1", "198.51.100.2", "203.0.113.7"},
+		{"[2001:db8::1]:5000", "198.51.100.1", "", "2001:db8::1"},
+		{"10.0.0.2:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"10.0.0.2:5000", "192.0.2.9, 198.51.100.1, 10.0.0.3", "", "198.51.100.1"},
+		{"10.0.0.2:5000", "10.0.0.4", "", "10.0.0.4"},
+		{"10.0.0.2:5000", "", "198.51.100.2", "198.51.100.2"},
+		{"10.0.0.2:5000", "", "", "10.0.0.2"},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if tt.xri != "" {
+			r.Header.Set("X-Real-IP", tt.xri)
+		}
+
+		if got := GetClientIP(r); got != tt.want {
+			t.Errorf("GetClientIP(%s, xff=%q, xri=%q) = %s, want %s", tt.remoteAddr, tt.xff, tt.xri, got, tt.want)
+		}
+	}
+}
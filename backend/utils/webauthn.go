@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"khairul169/garage-webui/schema"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+var ErrWebAuthnCloned = errors.New("credential may have been cloned and has been disabled")
+
+// webAuthnRelyingParty configures WebAuthn ceremonies from WEBAUTHN_* or the request host
+type webAuthnRelyingParty struct{}
+
+var WebAuthn = &webAuthnRelyingParty{}
+
+// Get returns the relying party for a request. WEBAUTHN_RP_ID and WEBAUTHN_RP_ORIGINS
+// should be set when the UI is served behind a reverse proxy.
+func (w *webAuthnRelyingParty) Get(r *http.Request) (*webauthn.WebAuthn, error) {
+	origins := splitList(GetEnv("WEBAUTHN_RP_ORIGINS", ""))
+	if len(origins) == 0 {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		origins = []string{scheme + "://" + r.Host}
+	}
+
+	rpID := GetEnv("WEBAUTHN_RP_ID", "")
+	if rpID == "" {
+		rpID = r.Host
+		if host, _, err := net.SplitHostPort(r.Host); err == nil {
+			rpID = host
+		}
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: GetEnv("WEBAUTHN_RP_NAME", "Garage Web UI"),
+		RPOrigins:     origins,
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			ResidentKey:      protocol.ResidentKeyRequirementPreferred,
+			UserVerification: protocol.VerificationPreferred,
+		},
+	})
+}
+
+// WebAuthnUser adapts a user and its credentials to the webauthn.User interface
+type WebAuthnUser struct {
+	User        *schema.User
+	Credentials []*schema.WebAuthnCredential
+}
+
+func (u *WebAuthnUser) WebAuthnID() []byte {
+	return []byte(u.User.ID)
+}
+
+func (u *WebAuthnUser) WebAuthnName() string {
+	return u.User.Username
+}
+
+func (u *WebAuthnUser) WebAuthnDisplayName() string {
+	return u.User.Username
+}
+
+func (u *WebAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, 0, len(u.Credentials))
+	for _, credential := range u.Credentials {
+		transports := make([]protocol.AuthenticatorTransport, 0, len(credential.Transports))
+		for _, transport := range credential.Transports {
+			transports = append(transports, protocol.AuthenticatorTransport(transport))
+		}
+
+		credentials = append(credentials, webauthn.Credential{
+			ID:              credential.CredentialID,
+			PublicKey:       credential.PublicKey,
+			AttestationType: credential.AttestationType,
+			Transport:       transports,
+			Flags: webauthn.CredentialFlags{
+				BackupEligible: credential.BackupEligible,
+				BackupState:    credential.BackupState,
+			},
+			Authenticator: webauthn.Authenticator{
+				AAGUID:    credential.AAGUID,
+				SignCount: credential.SignCount,
+			},
+		})
+	}
+	return credentials
+}
+
+// GetWebAuthnUser loads a user together with its registered credentials
+func (db *Database) GetWebAuthnUser(userID string) (*WebAuthnUser, error) {
+	user, err := db.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	result := &WebAuthnUser{User: user}
+	for _, credential := range db.WebAuthnCredentials {
+		if credential.UserID == userID {
+			result.Credentials = append(result.Credentials, credential)
+		}
+	}
+
+	return result, nil
+}
+
+// WebAuthn credential operations
+
+func (db *Database) AddWebAuthnCredential(userID, name string, credential *webauthn.Credential) (*schema.WebAuthnCredential, error) {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.Users[userID]; !exists {
+		return nil, errors.New("user not found")
+	}
+
+	for _, existing := range db.WebAuthnCredentials {
+		if bytes.Equal(existing.CredentialID, credential.ID) {
+			return nil, errors.New("credential is already registered")
+		}
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, transport := range credential.Transport {
+		transports = append(transports, string(transport))
+	}
+
+	if name == "" {
+		name = "Passkey"
+	}
+
+	result := &schema.WebAuthnCredential{
+		ID:              GenerateID(),
+		UserID:          userID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transports,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+		CreatedAt:       time.Now(),
+	}
+	db.WebAuthnCredentials[result.ID] = result
+
+	if err := db.saveUnsafe(); err != nil {
+		return nil, err
+	}
+
+	return sanitizeWebAuthnCredential(result), nil
+}
+
+// ListWebAuthnCredentials returns the credentials of a user, newest first
+func (db *Database) ListWebAuthnCredentials(userID string) ([]*schema.WebAuthnCredential, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	credentials := make([]*schema.WebAuthnCredential, 0)
+	for _, credential := range db.WebAuthnCredentials {
+		if credential.UserID == userID {
+			credentials = append(credentials, sanitizeWebAuthnCredential(credential))
+		}
+	}
+
+	sort.Slice(credentials, func(i, j int) bool {
+		return credentials[i].CreatedAt.After(credentials[j].CreatedAt)
+	})
+
+	return credentials, nil
+}
+
+// UpdateWebAuthnLogin stores the sign count after a login. A counter that did not
+// increase indicates a cloned authenticator, in which case the credential is removed.
+func (db *Database) UpdateWebAuthnLogin(credential *webauthn.Credential) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	for id, stored := range db.WebAuthnCredentials {
+		if !bytes.Equal(stored.CredentialID, credential.ID) {
+			continue
+		}
+
+		if credential.Authenticator.CloneWarning {
+			delete(db.WebAuthnCredentials, id)
+			if err := db.saveUnsafe(); err != nil {
+				return err
+			}
+			return ErrWebAuthnCloned
+		}
+
+		now := time.Now()
+		stored.SignCount = credential.Authenticator.SignCount
+		stored.BackupState = credential.Flags.BackupState
+		stored.LastUsedAt = &now
+		return db.saveUnsafe()
+	}
+
+	return errors.New("credential not found")
+}
+
+func (db *Database) DeleteWebAuthnCredential(id string) error {
+	db.mutex.Lock()
+	defer db.mutex.Unlock()
+
+	if _, exists := db.WebAuthnCredentials[id]; !exists {
+		return errors.New("credential not found")
+	}
+
+	delete(db.WebAuthnCredentials, id)
+	return db.saveUnsafe()
+}
+
+func (db *Database) GetWebAuthnCredential(id string) (*schema.WebAuthnCredential, error) {
+	db.mutex.RLock()
+	defer db.mutex.RUnlock()
+
+	credential, exists := db.WebAuthnCredentials[id]
+	if !exists {
+		return nil, errors.New("credential not found")
+	}
+
+	return sanitizeWebAuthnCredential(credential), nil
+}
+
+// sanitizeWebAuthnCredential returns a copy of the credential without its public key
+func sanitizeWebAuthnCredential(credential *schema.WebAuthnCredential) *schema.WebAuthnCredential {
+	result := *credential
+	result.PublicKey = nil
+	return &result
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -5,6 +5,7 @@ import MainLayout from "@/components/layouts/main-layout";
 import { BASE_PATH } from "@/lib/consts";
 
 const LoginPage = lazy(() => import("@/pages/auth/login"));
+const ChangePasswordPage = lazy(() => import("@/pages/auth/change-password"));
 const ClusterPage = lazy(() => import("@/pages/cluster/page"));
 const HomePage = lazy(() => import("@/pages/home/page"));
 const BucketsPage = lazy(() => import("@/pages/buckets/page"));
@@ -22,6 +23,10 @@ const router = createBrowserRouter(
           path: "login",
           Component: LoginPage,
         },
+        {
+          path: "change-password",
+          Component: ChangePasswordPage,
+        },
       ],
     },
     {
@@ -53,6 +53,14 @@ const Sidebar = () => {
             <p className="text-xs text-center text-base-content/40">
               {auth.user.role}
             </p>
+            {(!auth.user.auth_source || auth.user.auth_source === "local") && (
+              <Link
+                to="/auth/change-password"
+                className="block text-xs text-center link link-hover mt-1"
+              >
+                Change password
+              </Link>
+            )}
           </div>
         )}
       </div>
@@ -1,8 +1,10 @@
 import { useAuth } from "@/hooks/useAuth";
-import { Navigate, Outlet } from "react-router-dom";
+import { Navigate, Outlet, useLocation } from "react-router-dom";
 
 const AuthLayout = () => {
   const auth = useAuth();
+  const { pathname } = useLocation();
+  const isChangePassword = pathname.endsWith("/auth/change-password");
 
   console.log("AuthLayout render:", {
     isLoading: auth.isLoading,
@@ -15,6 +17,18 @@ const AuthLayout = () => {
     return null;
   }
 
+  // The password can only be changed while logged in
+  if (isChangePassword) {
+    if (!auth.isAuthenticated) {
+      return <Navigate to="/auth/login" replace />;
+    }
+    return (
+      <div className="min-h-svh flex items-center justify-center">
+        <Outlet />
+      </div>
+    );
+  }
+
   if (auth.isAuthenticated) {
     console.log("AuthLayout: User authenticated, redirecting to /");
     return <Navigate to="/" replace />;
@@ -27,6 +27,10 @@ const MainLayout = () => {
     return <Navigate to="/auth/login" />;
   }
 
+  if (auth.isPasswordChangeRequired) {
+    return <Navigate to="/auth/change-password" replace />;
+  }
+
   return (
     <Drawer
       open={sidebar.isOpen}
@@ -16,6 +16,7 @@ export const useAuth = () => {
     isEnabled: data?.data?.enabled,
     isAuthenticated: data?.data?.authenticated,
     user: data?.data?.user,
+    isPasswordChangeRequired: !!data?.data?.password_change_required,
   };
 };
 
@@ -9,6 +9,15 @@ type FetchOptions = Omit<RequestInit, "headers" | "body"> & {
 
 export const API_URL = BASE_PATH + "/api";
 
+const CSRF_COOKIE = "csrf_token";
+
+const getCsrfToken = () => {
+  const cookie = document.cookie
+    .split("; ")
+    .find((item) => item.startsWith(CSRF_COOKIE + "="));
+  return cookie ? decodeURIComponent(cookie.split("=")[1]) : null;
+};
+
 export class APIError extends Error {
   status!: number;
 
@@ -38,6 +47,12 @@ const api = {
       headers["Content-Type"] = "application/json";
     }
 
+    const method = options?.method?.toUpperCase() || "GET";
+    const csrfToken = getCsrfToken();
+    if (method !== "GET" && method !== "HEAD" && csrfToken) {
+      headers["X-CSRF-Token"] = csrfToken;
+    }
+
     const res = await fetch(_url, {
       ...options,
       credentials: "include",
@@ -54,6 +69,15 @@ const api = {
       throw new APIError("unauthorized", res.status);
     }
 
+    if (
+      res.status === 403 &&
+      isJson &&
+      data?.message === "password change required"
+    ) {
+      window.location.href = utils.url("/auth/change-password");
+      throw new APIError(data.message, res.status);
+    }
+
     if (!res.ok) {
       const message = isJson
         ? data?.message
@@ -0,0 +1,83 @@
+import Button from "@/components/ui/button";
+import { zodResolver } from "@hookform/resolvers/zod";
+import { Card } from "react-daisyui";
+import { useForm } from "react-hook-form";
+import { useNavigate } from "react-router-dom";
+import { changePasswordSchema } from "./schema";
+import { InputField } from "@/components/ui/input";
+import { useChangePassword } from "./hooks";
+import { useAuth } from "@/hooks/useAuth";
+
+export default function ChangePasswordPage() {
+  const form = useForm({
+    resolver: zodResolver(changePasswordSchema),
+    defaultValues: {
+      current_password: "",
+      new_password: "",
+      confirm_password: "",
+    },
+  });
+  const changePassword = useChangePassword();
+  const { isPasswordChangeRequired } = useAuth();
+  const navigate = useNavigate();
+
+  return (
+    <form onSubmit={form.handleSubmit((v) => changePassword.mutate(v))}>
+      <Card className="w-full max-w-md" bordered>
+        <Card.Body>
+          <Card.Title tag="h2">Change Password</Card.Title>
+          <p className="text-base-content/60">
+            {isPasswordChangeRequired
+              ? "Your password has to be changed before you can continue"
+              : "Enter your current password and choose a new one"}
+          </p>
+
+          <InputField
+            form={form}
+            name="current_password"
+            title="Current Password"
+            type="password"
+            placeholder="Enter your current password"
+          />
+
+          <InputField
+            form={form}
+            name="new_password"
+            title="New Password"
+            type="password"
+            placeholder="Enter a new password"
+          />
+
+          <InputField
+            form={form}
+            name="confirm_password"
+            title="Confirm Password"
+            type="password"
+            placeholder="Enter the new password again"
+          />
+
+          <Card.Actions className="mt-4 gap-2">
+            <Button
+              type="submit"
+              color="primary"
+              className="w-full md:w-auto min-w-[100px]"
+              loading={changePassword.isPending}
+            >
+              Change Password
+            </Button>
+            {!isPasswordChangeRequired && (
+              <Button
+                type="button"
+                color="ghost"
+                className="w-full md:w-auto"
+                onClick={() => navigate("/", { replace: true })}
+              >
+                Cancel
+              </Button>
+            )}
+          </Card.Actions>
+        </Card.Body>
+      </Card>
+    </form>
+  );
+}
@@ -1,7 +1,7 @@
 import { useMutation, useQueryClient } from "@tanstack/react-query";
 import { useNavigate } from "react-router-dom";
 import { z } from "zod";
-import { loginSchema } from "./schema";
+import { changePasswordSchema, loginSchema } from "./schema";
 import api from "@/lib/api";
 import { toast } from "sonner";
 
@@ -28,3 +28,26 @@ export const useLogin = () => {
     },
   });
 };
+
+export const useChangePassword = () => {
+  const queryClient = useQueryClient();
+  const navigate = useNavigate();
+
+  return useMutation({
+    mutationFn: async (values: z.infer<typeof changePasswordSchema>) => {
+      const body = {
+        current_password: values.current_password,
+        new_password: values.new_password,
+      };
+      return api.post("/auth/password", { body });
+    },
+    onSuccess: async () => {
+      toast.success("Password changed");
+      await queryClient.invalidateQueries({ queryKey: ["auth"] });
+      navigate("/", { replace: true });
+    },
+    onError: (err) => {
+      toast.error(err?.message || "Unknown error");
+    },
+  });
+};
@@ -24,6 +24,7 @@ export interface User {
   role: Role;
   tenant_id?: string;
   enabled: boolean;
+  auth_source?: string;
   last_login?: string;
   created_at: string;
   updated_at: string;
@@ -87,6 +88,7 @@ export interface AuthStatusResponse {
   enabled: boolean;
   authenticated: boolean;
   user?: User;
+  password_change_required?: boolean;
 }
 
 export interface TenantStats {